@@ -0,0 +1,149 @@
+package fest
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"path"
+
+	"github.com/zilllaiss/fest/internal/glob"
+)
+
+// SymlinkPolicy specifies how symlinks are copied.
+type SymlinkPolicy int
+
+const (
+	// Copy the file or directory the symlink points to.
+	SymlinkFollow SymlinkPolicy = iota
+
+	// Copy the symlink itself. The Output must implement SymlinkOutput.
+	SymlinkPreserve
+
+	// Don't copy symlinks.
+	SymlinkSkip
+)
+
+// Transformer transforms the content of a copied file read from r into w.
+// p is the slash-separated path of the file inside the generated location.
+// A non-empty returned path renames the file to it.
+type Transformer func(p string, r io.Reader, w io.Writer) (string, error)
+
+type transform struct {
+	pattern string
+	fn      Transformer
+}
+
+// Asset is a file or a directory that will be copied to the generated location.
+// Patterns used by its methods are matched against slash-separated paths
+// relative to the copied directory, where `**` matches zero or more directories
+// and a pattern without a slash matches the file or directory name at any depth.
+type Asset struct {
+	src, dst string
+	isDir    bool
+
+	include, exclude []string
+	ignoreFile       string
+	contentOnly      bool
+
+	symlinks          SymlinkPolicy
+	confine           bool
+	modTime           bool
+	perm              bool
+	filePerm, dirPerm fs.FileMode
+
+	transforms []transform
+}
+
+// Include only copies the files matching any of patterns. Directories are
+// always walked. Only applies to CopyDir.
+func (a *Asset) Include(patterns ...string) *Asset {
+	a.include = append(a.include, patterns...)
+	return a
+}
+
+// Exclude skips the files and directories matching any of patterns.
+// Only applies to CopyDir.
+func (a *Asset) Exclude(patterns ...string) *Asset {
+	a.exclude = append(a.exclude, patterns...)
+	return a
+}
+
+// IgnoreFile skips the files and directories listed in the file name at the root
+// of the copied directory, using the syntax of .gitignore. The file itself is
+// never copied, and it's fine for it to not exist. Only applies to CopyDir.
+func (a *Asset) IgnoreFile(name string) *Asset {
+	a.ignoreFile = name
+	return a
+}
+
+// ContentOnly copies the content of the directory without the directory itself.
+// Only applies to CopyDir.
+func (a *Asset) ContentOnly() *Asset {
+	a.contentOnly = true
+	return a
+}
+
+// Symlinks sets how symlinks are copied. By default it's SymlinkFollow.
+func (a *Asset) Symlinks(policy SymlinkPolicy) *Asset {
+	a.symlinks = policy
+	return a
+}
+
+// Confine refuses to copy anything that resolves outside the source root
+// through symlinks, failing Generate instead. Symlinks are resolved lexically.
+func (a *Asset) Confine() *Asset {
+	a.confine = true
+	return a
+}
+
+// PreserveModTime keeps the modification time of the copied files.
+func (a *Asset) PreserveModTime() *Asset {
+	a.modTime = true
+	return a
+}
+
+// Perm sets the permission of the copied files and directories to file and dir
+// respectively, instead of using the source's.
+func (a *Asset) Perm(file, dir fs.FileMode) *Asset {
+	a.perm = true
+	a.filePerm, a.dirPerm = file.Perm(), dir.Perm()
+	return a
+}
+
+// Transform adds a Transformer for the copied files whose path inside the
+// generated location matches pattern. It runs after the Generator's ones.
+func (a *Asset) Transform(pattern string, fn Transformer) *Asset {
+	a.transforms = append(a.transforms, transform{pattern: pattern, fn: fn})
+	return a
+}
+
+// target returns the path of the asset inside the generated location.
+func (a *Asset) target() string {
+	if a.isDir && a.contentOnly {
+		return a.dst
+	}
+	return path.Join(a.dst, path.Base(a.src))
+}
+
+// filter returns a function reporting whether the path rel inside
+// the copied directory should be copied.
+func (a *Asset) filter(fsys fs.FS) (func(rel string, isDir bool) bool, error) {
+	var ignore *glob.Ignore
+	if len(a.ignoreFile) > 0 {
+		b, err := fs.ReadFile(fsys, path.Join(a.src, a.ignoreFile))
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+		ignore = glob.ParseIgnore(string(b))
+	}
+
+	return func(rel string, isDir bool) bool {
+		if rel == "." {
+			return true
+		}
+		if rel == a.ignoreFile || glob.MatchAny(a.exclude, rel) || ignore.Ignored(rel, isDir) {
+			return false
+		}
+		return isDir || len(a.include) == 0 || glob.MatchAny(a.include, rel)
+	}, nil
+}
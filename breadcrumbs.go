@@ -0,0 +1,41 @@
+package fest
+
+import (
+	"strings"
+
+	"github.com/zilllaiss/fest/temfest"
+)
+
+// breadcrumbs derives the breadcrumb list of r from its path, where each
+// ancestor is named after the title of the route at its path, or the path
+// segment if there is none. The site's name is used for the root.
+func (g *Generator) breadcrumbs(r *Route) temfest.BreadcrumbList {
+	list := temfest.BreadcrumbList{
+		Items: []temfest.Breadcrumb{{Name: g.siteName, URL: g.baseURL + "/"}},
+	}
+
+	p := strings.Trim(r.canonicalPath(), "/")
+	if len(p) == 0 {
+		return list
+	}
+
+	segments := strings.Split(p, "/")
+	for i, seg := range segments {
+		ancestor := r
+		if i < len(segments)-1 {
+			ancestor = g.findRoute("/" + strings.Join(segments[:i+1], "/"))
+		}
+
+		item := temfest.Breadcrumb{Name: seg, URL: g.baseURL + "/" + strings.Join(segments[:i+1], "/")}
+		if ancestor != nil {
+			item.URL = g.baseURL + ancestor.canonicalPath()
+			if ancestor.title != nil && len(*ancestor.title) > 0 {
+				item.Name = *ancestor.title
+			}
+		} else {
+			item.URL += "/"
+		}
+		list.Items = append(list.Items, item)
+	}
+	return list
+}
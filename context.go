@@ -0,0 +1,60 @@
+package fest
+
+import (
+	"context"
+	"time"
+)
+
+type ctxKey string
+
+const (
+	ctxKeyRoute ctxKey = "route"
+	ctxKeyError ctxKey = "error"
+)
+
+// renderInfo is the information of the route being rendered.
+type renderInfo struct {
+	title, path, canonicalURL string
+	siteName, env             string
+	page                      int
+	buildTime                 time.Time
+	data                      map[string]any
+	nav                       *NavItem
+}
+
+func getInfo(ctx context.Context) *renderInfo {
+	if info, ok := ctx.Value(ctxKeyRoute).(*renderInfo); ok {
+		return info
+	}
+	return &renderInfo{}
+}
+
+// The following functions get the information of the route being rendered
+// from ctx. They return the zero value when ctx is not a render context,
+// e.g. outside of Generate or Generator.Handler.
+
+// GetTitle gets the current router's title.
+func GetTitle(ctx context.Context) string { return getInfo(ctx).title }
+
+// GetPath gets the URL path of the current route, e.g. "/posts/first".
+func GetPath(ctx context.Context) string { return getInfo(ctx).path }
+
+// GetCanonicalURL gets the canonical URL of the current route. It's
+// root-relative when GeneratorConfig.BaseURL is not set.
+func GetCanonicalURL(ctx context.Context) string { return getInfo(ctx).canonicalURL }
+
+// GetSiteName gets the site's name.
+func GetSiteName(ctx context.Context) string { return getInfo(ctx).siteName }
+
+// GetPage gets the 1-based page number of the current route created by
+// NewPaginatedRoutes, or 0 for other routes.
+func GetPage(ctx context.Context) int { return getInfo(ctx).page }
+
+// GetBuildTime gets the time the site is built, see GeneratorConfig.BuildTime.
+func GetBuildTime(ctx context.Context) time.Time { return getInfo(ctx).buildTime }
+
+// GetEnv gets the environment name, see GeneratorConfig.Env.
+func GetEnv(ctx context.Context) string { return getInfo(ctx).env }
+
+// GetData gets the data set with key for the current route, see Route.SetData.
+func GetData(ctx context.Context, key string) any { return getInfo(ctx).data[key] }
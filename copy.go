@@ -0,0 +1,341 @@
+package fest
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/zilllaiss/fest/internal/glob"
+)
+
+var errOutsideRoot = errors.New("symlink resolves outside the source root")
+
+// dirFS is os.DirFS that also accepts paths leading outside of dir,
+// e.g. "../shared", the same as plain OS paths relative to dir.
+type dirFS struct {
+	fs.FS
+	dir string
+}
+
+func newDirFS(dir string) dirFS {
+	if abs, err := filepath.Abs(dir); err == nil {
+		dir = abs
+	}
+	return dirFS{FS: os.DirFS(dir), dir: dir}
+}
+
+// outside reports whether name is a clean path leading outside of dir.
+func outside(name string) bool {
+	return (name == ".." || strings.HasPrefix(name, "../")) && path.Clean(name) == name
+}
+
+func (d dirFS) Open(name string) (fs.File, error) {
+	if outside(name) {
+		return os.Open(filepath.Join(d.dir, filepath.FromSlash(name)))
+	}
+	return d.FS.Open(name)
+}
+
+func (d dirFS) ReadLink(name string) (string, error) {
+	if !fs.ValidPath(name) && !outside(name) {
+		return "", &fs.PathError{Op: "readlink", Path: name, Err: fs.ErrInvalid}
+	}
+	return os.Readlink(filepath.Join(d.dir, filepath.FromSlash(name)))
+}
+
+func (d dirFS) Lstat(name string) (fs.FileInfo, error) {
+	if !fs.ValidPath(name) && !outside(name) {
+		return nil, &fs.PathError{Op: "lstat", Path: name, Err: fs.ErrInvalid}
+	}
+	return os.Lstat(filepath.Join(d.dir, filepath.FromSlash(name)))
+}
+
+// copier copies an Asset from fsys to out.
+type copier struct {
+	out        Output
+	fsys       fs.FS
+	a          *Asset
+	keep       func(rel string, isDir bool) bool
+	transforms []transform
+}
+
+// newCopier creates a copier for a with the transformers of g
+// followed by the ones of a.
+func (g *Generator) newCopier(a *Asset) (*copier, error) {
+	c := &copier{
+		out:        g.out,
+		fsys:       g.fsys,
+		a:          a,
+		transforms: slices.Concat(g.transforms, a.transforms),
+	}
+	if !a.isDir {
+		c.keep = func(string, bool) bool { return true }
+		return c, nil
+	}
+
+	keep, err := a.filter(g.fsys)
+	if err != nil {
+		return nil, err
+	}
+	c.keep = keep
+	return c, nil
+}
+
+func (g *Generator) copyAsset(a *Asset) error {
+	c, err := g.newCopier(a)
+	if err != nil {
+		return err
+	}
+
+	if a.isDir {
+		srcInfo, err := fs.Stat(g.fsys, a.src)
+		if err != nil {
+			return err
+		}
+		if !srcInfo.IsDir() {
+			return errors.New("source is not a directory")
+		}
+	}
+
+	return c.copy(a.src, a.target(), ".", nil)
+}
+
+// copy copies src to dst, where rel is the path of src relative to the
+// copied directory and parents are the directories copied above src.
+func (c *copier) copy(src, dst, rel string, parents []string) error {
+	info, err := c.lstat(src)
+	if err != nil {
+		return err
+	}
+
+	if info.Mode()&fs.ModeSymlink != 0 {
+		switch c.a.symlinks {
+		case SymlinkSkip:
+			return nil
+		case SymlinkPreserve:
+			return c.symlink(src, dst)
+		}
+
+		if src, err = c.resolve(src); err != nil {
+			return err
+		}
+		if info, err = fs.Stat(c.fsys, src); err != nil {
+			return err
+		}
+	}
+
+	if !info.IsDir() {
+		return c.copyFile(src, dst, info)
+	}
+
+	if slices.Contains(parents, src) {
+		return fmt.Errorf("%v: symlink cycle", src)
+	}
+	parents = append(parents, src)
+
+	// with include patterns, only make the directories of the copied files
+	if len(c.a.include) == 0 {
+		mode := ternary(c.a.perm, c.a.dirPerm, info.Mode())
+		if err := c.out.Mkdir(dst, mode); err != nil {
+			return err
+		}
+	}
+
+	entries, err := fs.ReadDir(c.fsys, src)
+	if err != nil {
+		return err
+	}
+
+	for _, e := range entries {
+		p := path.Join(src, e.Name())
+		childRel := path.Join(rel, e.Name())
+
+		isDir := e.IsDir()
+		if e.Type()&fs.ModeSymlink != 0 && c.a.symlinks == SymlinkFollow {
+			info, err := fs.Stat(c.fsys, p)
+			isDir = err == nil && info.IsDir()
+		}
+		if !c.keep(childRel, isDir) {
+			continue
+		}
+
+		if err := c.copy(p, path.Join(dst, e.Name()), childRel, parents); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *copier) copyFile(src, dst string, info fs.FileInfo) error {
+	srcFile, err := c.fsys.Open(src)
+	if err != nil {
+		return err
+	}
+	defer srcFile.Close()
+
+	dst, r, err := c.transform(src, dst, srcFile)
+	if err != nil {
+		return err
+	}
+
+	mode := ternary(c.a.perm, c.a.filePerm, info.Mode())
+	modTime := ternary(c.a.modTime, info.ModTime(), time.Time{})
+
+	dstFile, err := c.out.Create(dst, mode, modTime)
+	if err != nil {
+		return err
+	}
+
+	if _, err = io.Copy(dstFile, r); err != nil {
+		dstFile.Close()
+		return err
+	}
+	return dstFile.Close()
+}
+
+// transform passes the content of src read from r through the transformers
+// matching dst in order, returning the final path and content.
+func (c *copier) transform(src, dst string, r io.Reader) (string, io.Reader, error) {
+	for _, t := range c.transforms {
+		if !glob.Match(t.pattern, dst) {
+			continue
+		}
+
+		var buf bytes.Buffer
+		rename, err := t.fn(dst, r, &buf)
+		if err != nil {
+			return "", nil, fmt.Errorf("error transforming \"%v\": %w", src, err)
+		}
+		if len(rename) > 0 {
+			dst = slashPath(rename)
+		}
+		r = &buf
+	}
+	return dst, r, nil
+}
+
+func (c *copier) symlink(src, dst string) error {
+	rl, ok := c.fsys.(fs.ReadLinkFS)
+	if !ok {
+		return fmt.Errorf("%v: source filesystem can't read symlinks", src)
+	}
+	out, ok := c.out.(SymlinkOutput)
+	if !ok {
+		return fmt.Errorf("%v: output can't create symlinks", src)
+	}
+
+	target, err := rl.ReadLink(src)
+	if err != nil {
+		return err
+	}
+	if _, inside := c.join(src, target); !inside && c.a.confine {
+		return fmt.Errorf("%v: %w", src, errOutsideRoot)
+	}
+	return out.Symlink(target, dst)
+}
+
+func (c *copier) lstat(name string) (fs.FileInfo, error) {
+	if rl, ok := c.fsys.(fs.ReadLinkFS); ok {
+		return rl.Lstat(name)
+	}
+	return fs.Stat(c.fsys, name)
+}
+
+// resolve resolves the symlink name into a path inside the source root.
+// If it resolves outside of it, an error is returned when the Asset is
+// confined, otherwise name is returned as is and left for fsys to follow.
+func (c *copier) resolve(name string) (string, error) {
+	rl, ok := c.fsys.(fs.ReadLinkFS)
+	if !ok {
+		if c.a.confine {
+			return "", fmt.Errorf("%v: source filesystem can't read symlinks", name)
+		}
+		return name, nil
+	}
+
+	p := name
+	for range 255 {
+		info, err := rl.Lstat(p)
+		if err != nil {
+			return "", err
+		}
+		if info.Mode()&fs.ModeSymlink == 0 {
+			return p, nil
+		}
+
+		target, err := rl.ReadLink(p)
+		if err != nil {
+			return "", err
+		}
+
+		var inside bool
+		if p, inside = c.join(p, target); !inside {
+			if c.a.confine {
+				return "", fmt.Errorf("%v: %w", name, errOutsideRoot)
+			}
+			return name, nil
+		}
+	}
+	return "", fmt.Errorf("%v: too many levels of symlinks", name)
+}
+
+// join lexically resolves the symlink target found at name into a path in
+// the source root, reporting whether it's inside the root.
+func (c *copier) join(name, target string) (string, bool) {
+	if filepath.IsAbs(target) {
+		d, ok := c.fsys.(dirFS)
+		if !ok {
+			return "", false
+		}
+		rel, err := filepath.Rel(d.dir, target)
+		if err != nil {
+			return "", false
+		}
+		target, name = filepath.ToSlash(rel), "."
+	}
+
+	p := path.Join(path.Dir(name), filepath.ToSlash(target))
+	return p, p != ".." && !strings.HasPrefix(p, "../")
+}
+
+// locate returns the path in the source filesystem of the copied file at rel,
+// relative to the target of the Asset, following the same rules as copying it.
+func (c *copier) locate(rel string) (string, bool) {
+	p := c.a.src
+	var cur string
+	for _, elem := range strings.Split(rel, "/") {
+		p = path.Join(p, elem)
+		cur = path.Join(cur, elem)
+
+		info, err := c.lstat(p)
+		if err != nil {
+			return "", false
+		}
+		isDir := info.IsDir()
+
+		if info.Mode()&fs.ModeSymlink != 0 {
+			if c.a.symlinks == SymlinkSkip {
+				return "", false
+			}
+			if p, err = c.resolve(p); err != nil {
+				return "", false
+			}
+			if info, err = fs.Stat(c.fsys, p); err != nil {
+				return "", false
+			}
+			isDir = info.IsDir()
+		}
+		if !c.keep(cur, isDir) {
+			return "", false
+		}
+	}
+	return p, true
+}
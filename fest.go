@@ -5,9 +5,16 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
 	"os"
+	"path"
 	"path/filepath"
+	"slices"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/zilllaiss/fest/temfest"
 
@@ -33,25 +40,20 @@ const (
 	SiteNameNone
 )
 
-type srcDst struct{ src, dst string }
-
-type ctxKey string
-
-const (
-	ctxKeyTitle ctxKey = "routes' titles"
-	ctxKeyError ctxKey = "error"
-)
-
-// GetTitle gets the current router's title.
-func GetTitle(ctx context.Context) string { return ctx.Value(ctxKeyTitle).(string) }
-
 // Generator contains configuration for static files generation.
 type Generator struct {
 	HeadBody HeadBody
 
 	src      string
+	fsys     fs.FS
 	dest     string
 	siteName string
+	baseURL  string
+	env      string
+
+	buildTime time.Time
+
+	withBreadcrumbs bool
 
 	baseConfig temfest.BaseConfig
 
@@ -61,9 +63,20 @@ type Generator struct {
 	ctx    context.Context
 	noBase bool
 	routes []*Route
-	root   *os.Root
+	out    Output
 
-	files, dirs []srcDst
+	layouts map[string]layout
+
+	navMu    sync.Mutex
+	navItems map[*Route]*NavItem
+
+	files, dirs []*Asset
+	transforms  []transform
+	generated   []*generatedFile
+
+	headers     []headerRule
+	headersFile HeadersFileOption
+	notFound    string
 }
 
 // GeneratorConfig is configurations for Generator.
@@ -77,9 +90,18 @@ type GeneratorConfig struct {
 	// By default it's "."
 	Source string
 
+	// SourceFS is the filesystem used for finding necessary files, e.g.
+	// an embed.FS. If set, Source and FEST_SRC are ignored, and copied
+	// paths must be valid fs.FS paths, i.e. they can't contain "..".
+	SourceFS fs.FS
+
 	// Directory where static files will be generated. By default it's "./dist".
 	Destination string
 
+	// Output is where the generated files will be written. By default
+	// it's the Destination directory, see NewDirOutput.
+	Output Output
+
 	// SiteNameOption is the position in the title where the site's
 	// should be rendered. By default it's SiteNameBack.
 	SiteNameOption SiteNameOption
@@ -88,8 +110,33 @@ type GeneratorConfig struct {
 	// By default it's " - ".
 	Seperator string
 
-	// BaseConfig is temfest.Base config.
+	// BaseConfig is temfest.Base config. Its Meta is inherited by all routes.
 	BaseConfig temfest.BaseConfig
+
+	// BaseURL is the absolute URL of the site, e.g. "https://example.com".
+	// If set, it's used to fill the canonical URL of each route and to make
+	// root-relative meta image URLs absolute.
+	BaseURL string
+
+	// Breadcrumbs adds a schema.org BreadcrumbList derived from the path
+	// of each route to its `<head>`. It's no-op with NoBase.
+	Breadcrumbs bool
+
+	// NotFound is the path of the route or copied file that Handler
+	// serves with 404 status. By default it's "/404.html".
+	NotFound string
+
+	// HeadersFile is the format of the headers file that will be generated
+	// when any header is set. By default it's HeadersFileNetlify.
+	HeadersFile HeadersFileOption
+
+	// Env is the environment name available with GetEnv, e.g. "development".
+	// FEST_ENV overrides it if set. By default it's "production".
+	Env string
+
+	// BuildTime is the time available with GetBuildTime. By default it's
+	// the time the Generator is created.
+	BuildTime time.Time
 }
 
 // NewGenerator creates a new generator. Use nil to use default configs
@@ -106,8 +153,19 @@ func NewGenerator(ctx context.Context, siteName string, config *GeneratorConfig)
 	festDest, ok := os.LookupEnv("FEST_DEST")
 	g.dest = ternary(ok, festDest, config.Destination)
 
+	festEnv, ok := os.LookupEnv("FEST_ENV")
+	g.env = ternary(ok, festEnv, config.Env)
+	if len(g.env) < 1 {
+		g.env = "production"
+	}
+
 	festSrc, ok := os.LookupEnv("FEST_SRC")
 	g.src = ternary(ok, festSrc, config.Source)
+	g.fsys = config.SourceFS
+
+	if g.fsys == nil {
+		g.fsys = newDirFS(ternary(len(g.src) > 0, g.src, wd))
+	}
 
 	g.ctx = ctx
 	g.siteName = siteName
@@ -115,6 +173,16 @@ func NewGenerator(ctx context.Context, siteName string, config *GeneratorConfig)
 	g.siteTitleOption = config.SiteNameOption
 	g.seperator = config.Seperator
 	g.baseConfig = config.BaseConfig
+	g.baseURL = strings.TrimSuffix(config.BaseURL, "/")
+	g.withBreadcrumbs = config.Breadcrumbs
+	g.headersFile = config.HeadersFile
+	g.out = config.Output
+	g.notFound = ternary(len(config.NotFound) > 0, config.NotFound, "/404.html")
+	g.buildTime = ternary(config.BuildTime.IsZero(), time.Now(), config.BuildTime)
+
+	if g.out == nil {
+		g.out = NewDirOutput(g.dest)
+	}
 
 	return g
 }
@@ -125,6 +193,7 @@ func (g *Generator) AddRoute(path string, comp templ.Component) *Route {
 	r := g.inspect(path, comp)
 
 	g.routes = append(g.routes, r)
+	g.resetNav()
 	return r
 }
 
@@ -147,138 +216,254 @@ func (g *Generator) AddRouteFunc(
 	r.comp = t
 
 	g.routes = append(g.routes, r)
+	g.resetNav()
 	return r
 }
 
 // Context returns the generator context.
 func (g *Generator) Context() context.Context { return g.ctx }
 
+// SourceFS returns the filesystem g finds the necessary files from.
+// It can be passed to other packages' fs.FS variants, such as
+// markdown.MarkdownProcessor.ParseFilesFS, to use the same source.
+func (g *Generator) SourceFS() fs.FS { return g.fsys }
+
 // CopyFile copies src that is a file to the dst inside generated location.
 // Relative from Source of g.
-func (g *Generator) CopyFile(src, dst string) {
-	path := ternary(len(g.src) > 0, g.src, wd)
-	g.files = append(g.files, srcDst{
-		src: filepath.Join(path, src),
-		dst: filepath.Join(dst, filepath.Base(src)),
-	})
+func (g *Generator) CopyFile(src, dst string) *Asset {
+	a := &Asset{src: slashPath(src), dst: slashPath(dst)}
+	g.files = append(g.files, a)
+	return a
 }
 
 // CopyDir copies src that is a directory to dst inside generated location.
-// Will also copy the directory instead of the content-only.
-// Relative from Source set of g.
-func (g *Generator) CopyDir(src, dst string) {
-	path := ternary(len(g.src) > 0, g.src, wd)
-	g.dirs = append(g.dirs, srcDst{
-		src: filepath.Join(path, src),
-		dst: filepath.Join(dst, filepath.Base(src)),
-	})
+// Will also copy the directory instead of the content-only, unless
+// Asset.ContentOnly is used. Relative from Source set of g.
+func (g *Generator) CopyDir(src, dst string) *Asset {
+	a := &Asset{src: slashPath(src), dst: slashPath(dst), isDir: true}
+	g.dirs = append(g.dirs, a)
+	return a
 }
 
-// Generate generates all the components added to g.
-func (g *Generator) Generate() error {
-	if err := os.MkdirAll(g.dest, 0o744); err != nil {
-		return fmt.Errorf("error making dir: %w", err)
-	}
+// generatedFile is a file whose content is written by fn.
+type generatedFile struct {
+	dst string
+	fn  func(w io.Writer) error
+}
 
-	root, err := os.OpenRoot(g.dest)
-	if err != nil {
-		return fmt.Errorf("error opening root: %w", err)
-	}
-	g.root = root
+// AddFile adds a file at dst inside generated location whose content is
+// written by fn. It's useful for other packages to generate files such as
+// resized images or stylesheets. All errors returned from fn will be
+// handled in g.Generate method.
+func (g *Generator) AddFile(dst string, fn func(w io.Writer) error) {
+	g.generated = append(g.generated, &generatedFile{dst: slashPath(dst), fn: fn})
+}
 
-	defer g.root.Close()
+// Transform adds a Transformer for all copied files whose path inside the
+// generated location matches pattern, e.g. "*.js" or "assets/**/*.css".
+// Transformers run in the order they are added, each reading the output
+// of the previous one, and a renamed file is matched with its new path.
+func (g *Generator) Transform(pattern string, fn Transformer) *Generator {
+	g.transforms = append(g.transforms, transform{pattern: pattern, fn: fn})
+	return g
+}
 
-	val := g.ctx.Value(ctxKeyError)
-	if val != nil {
-		errs := val.(map[string]error)
-		var complete error
-		route := RouteError{complete: complete}
-		for k, err := range errs {
-			complete = fmt.Errorf("%v: %w; ", k, err)
-		}
-		return fmt.Errorf("%w (%v)", route, complete.Error())
+// Generate generates all the components added to g.
+func (g *Generator) Generate() (err error) {
+	if err := g.routeErr(); err != nil {
+		return err
 	}
 
+	defer func() {
+		if cerr := g.out.Close(); cerr != nil && err == nil {
+			err = fmt.Errorf("error closing output: %w", cerr)
+		}
+	}()
+
 	for _, v := range g.dirs {
-		if err := copyDir(v.src, filepath.Join(g.dest, v.dst)); err != nil {
-			return fmt.Errorf("error copying \"%v\" to \"%v\": %w", v.src, v.dst, err)
+		if err := g.copyAsset(v); err != nil {
+			return fmt.Errorf("error copying \"%v\" to \"%v\": %w", v.src, v.target(), err)
 		}
 	}
 
 	for _, v := range g.files {
-		if err := copyFile(v.src, filepath.Join(g.dest, v.dst)); err != nil {
-			return fmt.Errorf("error copying \"%v\" to \"%v\" %w", v.src, v.dst, err)
+		if err := g.copyAsset(v); err != nil {
+			return fmt.Errorf("error copying \"%v\" to \"%v\" %w", v.src, v.target(), err)
 		}
 	}
 
-	for _, r := range g.routes {
-		var filename string
-		var dir string
-		var path string
-
-		if r.isHTMLFile {
-			// filename = filepath.Base(dir)
-			dir = filepath.Dir(r.path)
-			path = r.path
-		} else {
-			filename = "index.html"
-			dir = r.path
-			path = filepath.Join(r.path, filename)
+	for _, v := range g.generated {
+		if err := g.generateFile(v); err != nil {
+			return fmt.Errorf("error generating \"%v\": %w", v.dst, err)
 		}
+	}
 
-		// why no MkdirAll for root?
-		if err := os.MkdirAll(filepath.Join(g.dest, dir), 0o744); err != nil {
-			return fmt.Errorf("error while making parent directory %v: %w", g.dest, err)
+	for _, r := range g.routes {
+		if err := g.generateRoute(r); err != nil {
+			return err
 		}
+	}
 
-		f, err := g.root.Create(path)
+	if rules := g.headerRules(); len(rules) > 0 && g.headersFile != HeadersFileNone {
+		f, err := g.out.Create(headersFilename(g.headersFile), 0o644, time.Time{})
 		if err != nil {
-			return fmt.Errorf("error while creating path: %w", err)
+			return fmt.Errorf("error while creating headers file: %w", err)
+		}
+
+		if err := writeHeaders(f, rules, g.headersFile); err != nil {
+			f.Close()
+			return fmt.Errorf("error while writing headers file: %w", err)
 		}
-		defer f.Close()
-
-		var title, tc string
-
-		if r.title != nil {
-			rt := *r.title
-			tc = *r.title
-			switch g.siteTitleOption {
-			case SiteNameBack:
-				title = rt + g.seperator + g.siteName
-			case SiteNameFront:
-				title = g.siteName + g.seperator + rt
-			case SiteNameNone:
-				title = rt
-			default:
-				return errors.New("unrecognized SiteNameOption enum")
-			}
-		} else {
-			title = g.siteName
+		if err := f.Close(); err != nil {
+			return fmt.Errorf("error while writing headers file: %w", err)
 		}
+	}
+	return nil
+}
 
-		// override the base
-		if r.base != nil {
-			r.comp = temfest.Nest(r.base, r.comp)
-		} else if !g.noBase {
-			if r.baseConfig == nil {
-				r.baseConfig = &temfest.BaseConfig{}
-			}
+// routeErr returns the errors caught while adding routes, if any.
+func (g *Generator) routeErr() error {
+	val := g.ctx.Value(ctxKeyError)
+	if val == nil {
+		return nil
+	}
+	errs := val.(map[string]error)
+	var complete error
+	route := RouteError{complete: complete}
+	for k, err := range errs {
+		complete = fmt.Errorf("%v: %w; ", k, err)
+	}
+	return fmt.Errorf("%w (%v)", route, complete.Error())
+}
 
-			cp := ptr(g.baseConfig)
-			inheritChildValues(cp, r.baseConfig)
-			head := append(g.HeadBody.head, r.HeadBody.head...)
-			body := append(g.HeadBody.body, r.HeadBody.body...)
+func (g *Generator) generateFile(v *generatedFile) error {
+	f, err := g.out.Create(v.dst, 0o644, time.Time{})
+	if err != nil {
+		return err
+	}
+	if err := v.fn(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
 
-			r.comp = temfest.Base(title, r.comp, head, body, cp)
+func (g *Generator) generateRoute(r *Route) error {
+	path := r.path
+	if !r.isHTMLFile {
+		path = filepath.Join(r.path, "index.html")
+	}
+
+	comp, ctx, err := g.page(r)
+	if err != nil {
+		return err
+	}
+
+	f, err := g.out.Create(slashPath(path), 0o644, time.Time{})
+	if err != nil {
+		return fmt.Errorf("error while creating path: %w", err)
+	}
+
+	if err := comp.Render(ctx, f); err != nil {
+		f.Close()
+		return fmt.Errorf("error while rendering: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("error while writing %v: %w", path, err)
+	}
+	return nil
+}
+
+// page wraps the component of r with the base and returns it
+// along with the context it should be rendered with.
+func (g *Generator) page(r *Route) (templ.Component, context.Context, error) {
+	var title, tc string
+
+	if r.title != nil {
+		rt := *r.title
+		tc = *r.title
+		switch g.siteTitleOption {
+		case SiteNameBack:
+			title = rt + g.seperator + g.siteName
+		case SiteNameFront:
+			title = g.siteName + g.seperator + rt
+		case SiteNameNone:
+			title = rt
+		default:
+			return nil, nil, errors.New("unrecognized SiteNameOption enum")
 		}
+	} else {
+		title = g.siteName
+	}
 
-		newCtx := context.WithValue(g.ctx, ctxKeyTitle, tc)
+	meta := g.baseConfig.Meta
+	if r.baseConfig != nil {
+		inheritChildValues(&meta, &r.baseConfig.Meta)
+	}
+	if r.meta != nil {
+		inheritChildValues(&meta, r.meta)
+	}
+	g.fillMeta(&meta, r, ternary(r.title != nil, tc, g.siteName))
 
-		if err := r.comp.Render(newCtx, f); err != nil {
-			return fmt.Errorf("error while rendering: %w", err)
+	comp := r.comp
+	if len(r.layout) > 0 {
+		var err error
+		if comp, err = g.applyLayout(r, comp, tc, meta); err != nil {
+			return nil, nil, err
 		}
 	}
-	return nil
+
+	// override the base
+	if r.base != nil {
+		comp = temfest.Nest(r.base, comp)
+	} else if !g.noBase {
+		cp := ptr(g.baseConfig)
+		if r.baseConfig != nil {
+			inheritChildValues(cp, r.baseConfig)
+		}
+		cp.Meta = meta
+
+		head := append(slices.Clip(g.HeadBody.head), r.HeadBody.head...)
+		body := append(slices.Clip(g.HeadBody.body), r.HeadBody.body...)
+
+		if g.withBreadcrumbs {
+			head = append(head, temfest.JSONLD(g.breadcrumbs(r)))
+		}
+
+		comp = temfest.Base(title, comp, head, body, cp)
+	}
+
+	info := &renderInfo{
+		title:        tc,
+		path:         r.urlPath(),
+		canonicalURL: ternary(len(meta.CanonicalURL) > 0, meta.CanonicalURL, r.canonicalPath()),
+		siteName:     g.siteName,
+		env:          g.env,
+		page:         r.page,
+		buildTime:    g.buildTime,
+		data:         r.userData,
+		nav:          g.navTree()[r],
+	}
+	return comp, context.WithValue(g.ctx, ctxKeyRoute, info), nil
+}
+
+// fillMeta fills the unset fields of m that can be derived from r.
+func (g *Generator) fillMeta(m *temfest.Meta, r *Route, title string) {
+	if len(m.Title) < 1 {
+		m.Title = title
+	}
+	if len(m.SiteName) < 1 {
+		m.SiteName = g.siteName
+	}
+	if len(g.baseURL) < 1 {
+		return
+	}
+	if len(m.CanonicalURL) < 1 {
+		m.CanonicalURL = g.baseURL + r.canonicalPath()
+	}
+	if strings.HasPrefix(m.Image.URL, "/") && !strings.HasPrefix(m.Image.URL, "//") {
+		m.Image.URL = g.baseURL + m.Image.URL
+	}
 }
 
 func (g *Generator) inspect(path string, comp templ.Component) *Route {
@@ -286,7 +471,7 @@ func (g *Generator) inspect(path string, comp templ.Component) *Route {
 		path = string(path[1:])
 	}
 
-	r := &Route{path: path, comp: comp}
+	r := &Route{path: path, comp: comp, g: g}
 
 	if strings.HasSuffix(path, "html") {
 		r.isHTMLFile = true
@@ -307,6 +492,8 @@ func (g *Generator) addError(path string, err error) {
 
 // Route contains data necessary to generate a route.
 type Route struct {
+	g *Generator
+
 	path  string
 	comp  templ.Component
 	title *string
@@ -316,7 +503,19 @@ type Route struct {
 	// Only non-nil when overrided
 	base templ.Component
 
+	layout string
+	slots  map[string]templ.Component
+
+	// page number of paginated routes
+	page     int
+	userData map[string]any
+
+	weight int
+	menus  []string
+
 	baseConfig *temfest.BaseConfig
+	meta       *temfest.Meta
+	header     http.Header
 	HeadBody   HeadBody
 }
 
@@ -324,14 +523,88 @@ type Route struct {
 // the route will use site's name.
 func (r *Route) SetTitle(title string) *Route {
 	r.title = &title
+	r.g.resetNav()
 	return r
 }
 
+// BaseConfig sets the temfest.Base config of the route. Unset/empty field
+// will be inherited from the Generator's BaseConfig, see temfest.BaseConfig.
 func (r *Route) BaseConfig(conf temfest.BaseConfig) *Route {
 	r.baseConfig = &conf
 	return r
 }
 
+// SetMeta sets the page metadata of the route. Unset/empty field will
+// be inherited from the Generator's BaseConfig.
+func (r *Route) SetMeta(meta temfest.Meta) *Route {
+	r.meta = &meta
+	return r
+}
+
+// SetHeader sets a response header for the route that will be written
+// to the headers file.
+func (r *Route) SetHeader(key, value string) *Route {
+	if r.header == nil {
+		r.header = http.Header{}
+	}
+	r.header.Set(key, value)
+	return r
+}
+
+// urlPath returns the URL path of the route.
+func (r *Route) urlPath() string { return "/" + r.path }
+
+// canonicalPath returns the URL path the route is served at after Generate,
+// i.e. with a trailing slash for routes generated as index.html.
+func (r *Route) canonicalPath() string {
+	p := path.Clean("/" + r.path)
+	if r.isHTMLFile || p == "/" {
+		return p
+	}
+	return p + "/"
+}
+
+// Layout sets the name of the layout the route is rendered with,
+// see Generator.AddLayout.
+func (r *Route) Layout(name string) *Route {
+	r.layout = name
+	return r
+}
+
+// Slot sets the component of the layout's slot name.
+// The route's component is always the SlotMain.
+func (r *Route) Slot(name string, comp templ.Component) *Route {
+	if r.slots == nil {
+		r.slots = map[string]templ.Component{}
+	}
+	r.slots[name] = comp
+	return r
+}
+
+// SetData sets value with key to the route that can be got with GetData
+// when rendering.
+func (r *Route) SetData(key string, value any) *Route {
+	if r.userData == nil {
+		r.userData = map[string]any{}
+	}
+	r.userData[key] = value
+	return r
+}
+
+// SetWeight sets the weight of the route's navigation item, see NavItem.
+func (r *Route) SetWeight(weight int) *Route {
+	r.weight = weight
+	r.g.resetNav()
+	return r
+}
+
+// Menu adds the route's navigation item to the menus, see GetMenu.
+func (r *Route) Menu(names ...string) *Route {
+	r.menus = append(r.menus, names...)
+	r.g.resetNav()
+	return r
+}
+
 // OverrideBase overrides the base component. Note that it must have the implemented templ { children... }
 func (r *Route) OverrideBase(comp templ.Component) *Route {
 	r.base = comp
@@ -1,15 +1,31 @@
 package fest
 
 import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
 	"cmp"
+	"compress/gzip"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"io/fs"
+	"math"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"path"
 	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
+	"testing/fstest"
+	"time"
 
 	"github.com/PuerkitoBio/goquery"
+	"github.com/a-h/templ"
 	"github.com/zilllaiss/fest/internal/testfest"
 	"github.com/zilllaiss/fest/temfest"
 )
@@ -51,13 +67,14 @@ func TestGenerator(t *testing.T) {
 				BaseConfig: temfest.BaseConfig{
 					Lang:       "id",
 					CharSet:    "utf-8",
-					NoViewport: true,
+					NoViewport: temfest.True,
 				},
 			},
 		},
 		{
-			name:   "envs",
-			config: nil,
+			name:    "envs",
+			config:  nil,
+			genPath: filepath.Join(testPath, "envs"),
 			preRun: func() error {
 				err1 := os.Setenv("FEST_SRC", "tmp")
 				err2 := os.Setenv("FEST_DEST", filepath.Join(testPath, "envs"))
@@ -133,7 +150,7 @@ func TestGenerator(t *testing.T) {
 				return
 			}
 		})
-		if v.config != nil && v.config.BaseConfig.NoViewport && ok {
+		if v.config != nil && v.config.BaseConfig.NoViewport.IsTrue() && ok {
 			return errors.New("meta[name=viewport] exists")
 		}
 
@@ -168,3 +185,967 @@ func checkElAttr(err error, doc *goquery.Document, selector, attr, val string, a
 	}
 	return nil
 }
+
+func TestHeaders(t *testing.T) {
+	testPath := filepath.Join("tmp", "headers")
+	_ = os.RemoveAll(testPath)
+
+	data := []struct {
+		name   string
+		option HeadersFileOption
+		file   string
+		want   string
+	}{
+		{
+			name:   "netlify",
+			option: HeadersFileNetlify,
+			file:   "_headers",
+			want: "/assets/*\n  Cache-Control: max-age=31536000\n\n" +
+				"/about/\n  Content-Security-Policy: default-src 'self'\n  X-Frame-Options: DENY\n\n" +
+				"/posts/first/\n  X-Frame-Options: SAMEORIGIN\n",
+		},
+		{
+			name:   "json",
+			option: HeadersFileJSON,
+			file:   "_headers.json",
+			want:   `"path": "/posts/first/"`,
+		},
+	}
+
+	for _, v := range data {
+		t.Run(v.name, func(t *testing.T) {
+			dest := filepath.Join(testPath, v.name)
+			g := NewGenerator(context.Background(), v.name, &GeneratorConfig{
+				Destination: dest,
+				HeadersFile: v.option,
+			})
+			g.SetHeader("/assets/*", "Cache-Control", "max-age=31536000")
+
+			g.AddRoute("/", testfest.Simple())
+			g.AddRoute("/about", testfest.Simple()).
+				SetHeader("X-Frame-Options", "DENY").
+				SetHeader("Content-Security-Policy", "default-src 'self'")
+
+			NewRoutes("/posts/{s}", []string{"first"}).
+				SetHeader("X-Frame-Options", "DENY").
+				AddToGenerator(g, func(ctx context.Context, rp *RouteParam[string]) (templ.Component, error) {
+					rp.SetHeader("X-Frame-Options", "SAMEORIGIN")
+					return testfest.Simple(), nil
+				})
+
+			if err := g.Generate(); err != nil {
+				t.Fatal(err)
+			}
+
+			b, err := os.ReadFile(filepath.Join(dest, v.file))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(string(b), v.want) {
+				t.Errorf("expected %q in %v, found:\n%s", v.want, v.file, b)
+			}
+		})
+	}
+}
+
+func TestOutput(t *testing.T) {
+	generate := func(out Output) error {
+		g := NewGenerator(context.Background(), "output", &GeneratorConfig{Output: out})
+		g.CopyDir("examples/basic/assets", "/static")
+		g.AddRoute("/", testfest.Simple())
+		g.AddRoute("/posts/first", testfest.Simple())
+		return g.Generate()
+	}
+	want := []string{
+		"index.html",
+		"posts/first/index.html",
+		"static/assets/styles.css",
+		"static/assets/nested/styles.css",
+	}
+
+	t.Run("memory", func(t *testing.T) {
+		out := NewMemOutput()
+		if err := generate(out); err != nil {
+			t.Fatal(err)
+		}
+		for _, name := range want {
+			if _, err := fs.Stat(out, name); err != nil {
+				t.Error(err)
+			}
+		}
+
+		b, err := fs.ReadFile(out, "index.html")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(b), "<title>output</title>") {
+			t.Errorf("title not found in:\n%s", b)
+		}
+	})
+
+	t.Run("dir", func(t *testing.T) {
+		dir, outside := t.TempDir(), t.TempDir()
+		if err := os.Symlink(outside, filepath.Join(dir, "escape")); err != nil {
+			t.Skip(err)
+		}
+		out := NewDirOutput(dir)
+		defer out.Close()
+
+		// names resolving outside of the directory are refused
+		if err := out.Mkdir("escape/sub", 0o755); err == nil {
+			t.Error("expected error making a directory through a symlink")
+		}
+		if err := out.(SymlinkOutput).Symlink("target", "escape/link"); err == nil {
+			t.Error("expected error creating a symlink through a symlink")
+		}
+		if entries, _ := os.ReadDir(outside); len(entries) > 0 {
+			t.Errorf("expected nothing written outside, found %v", entries)
+		}
+	})
+
+	t.Run("zip", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := generate(NewZipOutput(&buf)); err != nil {
+			t.Fatal(err)
+		}
+		zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, name := range want {
+			if _, err := fs.Stat(zr, name); err != nil {
+				t.Error(err)
+			}
+		}
+	})
+
+	t.Run("tar.gz", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := generate(NewTarGzOutput(&buf)); err != nil {
+			t.Fatal(err)
+		}
+		gr, err := gzip.NewReader(&buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		found := map[string]bool{}
+		tr := tar.NewReader(gr)
+		for {
+			h, err := tr.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			found[h.Name] = true
+		}
+		for _, name := range want {
+			if !found[name] {
+				t.Errorf("%v not found in archive", name)
+			}
+		}
+	})
+}
+
+func TestHandler(t *testing.T) {
+	g := NewGenerator(context.Background(), "handler", nil)
+	g.CopyDir("examples/basic/assets", "")
+	g.CopyFile("examples/basic/404.html", "")
+	g.SetHeader("/assets/*", "Cache-Control", "max-age=3600")
+
+	g.AddRoute("/", testfest.Simple())
+	g.AddRoute("/about", testfest.Simple()).
+		SetTitle("About").
+		SetHeader("X-Frame-Options", "DENY")
+
+	h := g.Handler()
+
+	data := []struct {
+		path   string
+		code   int
+		header string
+		value  string
+		body   string
+	}{
+		{path: "/", code: http.StatusOK, body: "<title>handler</title>"},
+		{path: "/about", code: http.StatusOK, header: "X-Frame-Options", value: "DENY", body: "<title>About - handler</title>"},
+		{path: "/about/", code: http.StatusOK, header: "X-Frame-Options", value: "DENY"},
+		{path: "/about/index.html", code: http.StatusOK, body: "Hello, World"},
+		{path: "/assets/nested/styles.css", code: http.StatusOK, header: "Cache-Control", value: "max-age=3600"},
+		{path: "/assets/../../go.mod", code: http.StatusNotFound},
+		{path: "/missing", code: http.StatusNotFound, body: "<h1>Not found</h1>"},
+	}
+
+	for _, v := range data {
+		t.Run(v.path, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, v.path, nil))
+
+			if rec.Code != v.code {
+				t.Errorf("expected status %d, found %d", v.code, rec.Code)
+			}
+			if len(v.header) > 0 && rec.Header().Get(v.header) != v.value {
+				t.Errorf("expected header %v: %v, found %q", v.header, v.value, rec.Header().Get(v.header))
+			}
+			if !strings.Contains(rec.Body.String(), v.body) {
+				t.Errorf("expected %q in body:\n%s", v.body, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestSourceFS(t *testing.T) {
+	src := fstest.MapFS{
+		"theme/styles.css":      {Data: []byte("body {}")},
+		"theme/fonts/font.woff": {Data: []byte("font")},
+		"robots.txt":            {Data: []byte("User-agent: *")},
+	}
+	out := NewMemOutput()
+
+	g := NewGenerator(context.Background(), "fs", &GeneratorConfig{SourceFS: src, Output: out})
+	g.CopyDir("theme", "static")
+	g.CopyFile("robots.txt", "")
+
+	if err := g.Generate(); err != nil {
+		t.Fatal(err)
+	}
+	for name, f := range src {
+		if name != "robots.txt" {
+			name = path.Join("static", name)
+		}
+		b, err := fs.ReadFile(out, name)
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		if !bytes.Equal(b, f.Data) {
+			t.Errorf("%v: expected %q, found %q", name, f.Data, b)
+		}
+	}
+
+	// without SourceFS, paths outside of Source are copied as OS paths
+	out = NewMemOutput()
+	g = NewGenerator(context.Background(), "fs", &GeneratorConfig{Source: "examples/routes", Output: out})
+	g.CopyDir("../basic/assets", "static")
+	g.CopyFile("../basic/assets/styles.css", "")
+
+	if err := g.Generate(); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"static/assets/nested/styles.css", "styles.css"} {
+		if _, err := fs.Stat(out, name); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func TestCopyDirFilters(t *testing.T) {
+	src := fstest.MapFS{
+		"theme/.festignore":         {Data: []byte("*.bak\nfixtures/\n")},
+		"theme/.DS_Store":           {},
+		"theme/styles.css":          {},
+		"theme/styles.scss":         {},
+		"theme/old.css.bak":         {},
+		"theme/js/app.js":           {},
+		"theme/js/app.test.js":      {},
+		"theme/fixtures/data.json":  {},
+		"theme/img/nested/logo.png": {},
+	}
+
+	data := []struct {
+		name  string
+		setup func(a *Asset)
+		want  []string
+	}{
+		{
+			name: "exclude",
+			setup: func(a *Asset) {
+				a.Exclude(".DS_Store", "*.scss", "**/*.test.js").IgnoreFile(".festignore")
+			},
+			want: []string{
+				"theme/img/nested/logo.png",
+				"theme/js/app.js",
+				"theme/styles.css",
+			},
+		},
+		{
+			name: "include content only",
+			setup: func(a *Asset) {
+				a.Include("*.css", "img/**").ContentOnly()
+			},
+			want: []string{
+				"img/nested/logo.png",
+				"styles.css",
+			},
+		},
+	}
+
+	for _, v := range data {
+		t.Run(v.name, func(t *testing.T) {
+			out := NewMemOutput()
+			g := NewGenerator(context.Background(), v.name, &GeneratorConfig{SourceFS: src, Output: out})
+			v.setup(g.CopyDir("theme", ""))
+
+			if err := g.Generate(); err != nil {
+				t.Fatal(err)
+			}
+
+			var found []string
+			err := fs.WalkDir(out, ".", func(p string, d fs.DirEntry, err error) error {
+				if err == nil && !d.IsDir() {
+					found = append(found, p)
+				}
+				return err
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(found, v.want) {
+				t.Errorf("expected %v, found %v", v.want, found)
+			}
+		})
+	}
+}
+
+func TestCopyPolicies(t *testing.T) {
+	tmp := t.TempDir()
+	src := filepath.Join(tmp, "src")
+	outside := filepath.Join(tmp, "outside.txt")
+	mtime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	err := cmp.Or(
+		os.MkdirAll(filepath.Join(src, "assets", "dir"), 0o755),
+		os.WriteFile(filepath.Join(src, "assets", "file.txt"), []byte("file"), 0o600),
+		os.WriteFile(filepath.Join(src, "assets", "dir", "nested.txt"), []byte("nested"), 0o600),
+		os.WriteFile(outside, []byte("outside"), 0o644),
+		os.Chtimes(filepath.Join(src, "assets", "file.txt"), mtime, mtime),
+		os.Symlink("file.txt", filepath.Join(src, "assets", "link.txt")),
+		os.Symlink("dir", filepath.Join(src, "assets", "linkdir")),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	generate := func(t *testing.T, setup func(a *Asset)) (string, error) {
+		dest := filepath.Join(tmp, "dist", t.Name())
+		g := NewGenerator(context.Background(), "policies", &GeneratorConfig{Source: src, Destination: dest})
+		setup(g.CopyDir("assets", ""))
+		return filepath.Join(dest, "assets"), g.Generate()
+	}
+
+	t.Run("follow", func(t *testing.T) {
+		dest, err := generate(t, func(a *Asset) {})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for name, want := range map[string]string{"link.txt": "file", "linkdir/nested.txt": "nested"} {
+			b, err := os.ReadFile(filepath.Join(dest, name))
+			if err != nil || string(b) != want {
+				t.Errorf("%v: expected %q, found %q (%v)", name, want, b, err)
+			}
+		}
+	})
+
+	t.Run("skip", func(t *testing.T) {
+		dest, err := generate(t, func(a *Asset) { a.Symlinks(SymlinkSkip) })
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, name := range []string{"link.txt", "linkdir"} {
+			if _, err := os.Lstat(filepath.Join(dest, name)); !errors.Is(err, fs.ErrNotExist) {
+				t.Errorf("%v: expected to be skipped, found %v", name, err)
+			}
+		}
+	})
+
+	t.Run("preserve", func(t *testing.T) {
+		dest, err := generate(t, func(a *Asset) { a.Symlinks(SymlinkPreserve) })
+		if err != nil {
+			t.Fatal(err)
+		}
+		if target, err := os.Readlink(filepath.Join(dest, "link.txt")); err != nil || target != "file.txt" {
+			t.Errorf("expected symlink to file.txt, found %q (%v)", target, err)
+		}
+	})
+
+	t.Run("modtime and perm", func(t *testing.T) {
+		dest, err := generate(t, func(a *Asset) { a.PreserveModTime().Perm(0o644, 0o755) })
+		if err != nil {
+			t.Fatal(err)
+		}
+		info, err := os.Stat(filepath.Join(dest, "file.txt"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !info.ModTime().Equal(mtime) {
+			t.Errorf("expected mod time %v, found %v", mtime, info.ModTime())
+		}
+		if info.Mode().Perm() != 0o644 {
+			t.Errorf("expected mode 0644, found %v", info.Mode().Perm())
+		}
+	})
+
+	t.Run("confine", func(t *testing.T) {
+		if err := os.Symlink(outside, filepath.Join(src, "assets", "escape.txt")); err != nil {
+			t.Fatal(err)
+		}
+		defer os.Remove(filepath.Join(src, "assets", "escape.txt"))
+
+		if _, err := generate(t, func(a *Asset) {}); err != nil {
+			t.Errorf("expected unconfined copy to succeed, found %v", err)
+		}
+		if _, err := generate(t, func(a *Asset) { a.Confine() }); !errors.Is(err, errOutsideRoot) {
+			t.Errorf("expected %v, found %v", errOutsideRoot, err)
+		}
+	})
+}
+
+func TestTransform(t *testing.T) {
+	src := fstest.MapFS{
+		"assets/app.js":      {Data: []byte(`const version = "__VERSION__";`)},
+		"assets/styles.scss": {Data: []byte("body {}")},
+		"assets/bad.txt":     {Data: []byte("bad")},
+	}
+	replace := func(old, new string) Transformer {
+		return func(p string, r io.Reader, w io.Writer) (string, error) {
+			b, err := io.ReadAll(r)
+			if err != nil {
+				return "", err
+			}
+			_, err = w.Write(bytes.ReplaceAll(b, []byte(old), []byte(new)))
+			return "", err
+		}
+	}
+
+	out := NewMemOutput()
+	g := NewGenerator(context.Background(), "transform", &GeneratorConfig{SourceFS: src, Output: out})
+	g.Transform("*.js", replace("__VERSION__", "v1.0.0"))
+	g.CopyDir("assets", "").
+		Exclude("*.txt").
+		Transform("*.scss", func(p string, r io.Reader, w io.Writer) (string, error) {
+			_, err := io.Copy(w, r)
+			return strings.TrimSuffix(p, ".scss") + ".css", err
+		}).
+		Transform("*.css", replace("body", "html"))
+
+	if err := g.Generate(); err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]string{
+		"assets/app.js":     `const version = "v1.0.0";`,
+		"assets/styles.css": "html {}",
+	} {
+		b, err := fs.ReadFile(out, name)
+		if err != nil || string(b) != want {
+			t.Errorf("%v: expected %q, found %q (%v)", name, want, b, err)
+		}
+	}
+
+	// Handler serves the renamed files at their new path only
+	for p, want := range map[string]string{
+		"/assets/app.js":      `const version = "v1.0.0";`,
+		"/assets/styles.css":  "html {}",
+		"/assets/styles.scss": "404 page not found\n",
+	} {
+		rec := httptest.NewRecorder()
+		g.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, p, nil))
+		if rec.Body.String() != want {
+			t.Errorf("%v: expected %q, found %q", p, want, rec.Body.String())
+		}
+	}
+
+	g = NewGenerator(context.Background(), "transform", &GeneratorConfig{SourceFS: src, Output: NewMemOutput()})
+	g.CopyFile("assets/bad.txt", "").
+		Transform("*.txt", func(string, io.Reader, io.Writer) (string, error) {
+			return "", errors.New("failed")
+		})
+	if err := g.Generate(); err == nil || !strings.Contains(err.Error(), "assets/bad.txt") {
+		t.Errorf("expected error with the source path, found %v", err)
+	}
+}
+
+func TestMeta(t *testing.T) {
+	out := NewMemOutput()
+	g := NewGenerator(context.Background(), "Meta", &GeneratorConfig{
+		Output:  out,
+		BaseURL: "https://example.com/",
+		BaseConfig: temfest.BaseConfig{Meta: temfest.Meta{
+			Type:        "website",
+			TwitterCard: "summary_large_image",
+			Image:       temfest.MetaImage{URL: "/og.png", Width: 1200, Height: 630},
+		}},
+	})
+	g.AddRoute("/", testfest.Simple())
+
+	published := time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC)
+	NewRoutes("/posts/{s}", []string{"first", "second"}).
+		SetMeta(temfest.Meta{Type: "article", Author: "Zill"}).
+		AddToGenerator(g, func(ctx context.Context, rp *RouteParam[string]) (templ.Component, error) {
+			if rp.GetSlug() == "first" {
+				rp.SetMeta(temfest.Meta{Description: "The first post", PublishedTime: published})
+			}
+			return testfest.Simple(), nil
+		})
+
+	if err := g.Generate(); err != nil {
+		t.Fatal(err)
+	}
+
+	data := []struct {
+		file, selector, attr, want string
+		found                      bool
+	}{
+		{"index.html", `meta[property="og:type"]`, "content", "website", true},
+		{"index.html", `meta[property="og:title"]`, "content", "Meta", true},
+		{"index.html", `link[rel="canonical"]`, "href", "https://example.com/", true},
+		{"index.html", `meta[property="og:image"]`, "content", "https://example.com/og.png", true},
+		{"index.html", `meta[property="og:image:width"]`, "content", "1200", true},
+		{"index.html", `meta[name="twitter:card"]`, "content", "summary_large_image", true},
+		{"posts/first/index.html", `meta[property="og:type"]`, "content", "article", true},
+		{"posts/first/index.html", `meta[property="og:title"]`, "content", "first", true},
+		{"posts/first/index.html", `meta[property="og:url"]`, "content", "https://example.com/posts/first/", true},
+		{"posts/first/index.html", `meta[name="author"]`, "content", "Zill", true},
+		{"posts/first/index.html", `meta[name="description"]`, "content", "The first post", true},
+		{"posts/first/index.html", `meta[property="article:published_time"]`, "content", "2024-05-06T07:08:09Z", true},
+		{"posts/second/index.html", `meta[name="author"]`, "content", "Zill", true},
+		{"posts/second/index.html", `meta[name="description"]`, "content", "", false},
+	}
+
+	for _, v := range data {
+		f, err := out.Open(v.file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		doc, err := goquery.NewDocumentFromReader(f)
+		f.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		el := doc.Find(v.selector)
+		if !v.found {
+			if el.Length() > 0 {
+				t.Errorf("%v: expected no %v", v.file, v.selector)
+			}
+			continue
+		}
+		if err := checkElAttr(nil, doc, v.selector, v.attr, v.want, true); err != nil {
+			t.Errorf("%v: %v", v.file, err)
+		}
+	}
+}
+
+func TestJSONLD(t *testing.T) {
+	out := NewMemOutput()
+	g := NewGenerator(context.Background(), "LD", &GeneratorConfig{
+		Output:      out,
+		BaseURL:     "https://example.com",
+		Breadcrumbs: true,
+	})
+	g.AddRoute("/", testfest.Simple())
+	g.AddRoute("/posts", testfest.Simple()).SetTitle("Posts")
+	g.AddRoute("/posts/first", testfest.Simple()).SetTitle("First")
+	g.AddRoute("/docs/guide", testfest.Simple()).SetTitle("Guide").
+		HeadBody.Head(temfest.JSONLD(temfest.Article{
+		Headline: "</script><script>alert(1)</script>",
+		Author:   []temfest.Person{{Name: "Zill"}},
+	}))
+
+	if err := g.Generate(); err != nil {
+		t.Fatal(err)
+	}
+
+	scripts := func(file string) []map[string]any {
+		f, err := out.Open(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		doc, err := goquery.NewDocumentFromReader(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var res []map[string]any
+		doc.Find(`script[type="application/ld+json"]`).Each(func(_ int, s *goquery.Selection) {
+			var v map[string]any
+			if err := json.Unmarshal([]byte(s.Text()), &v); err != nil {
+				t.Errorf("%v: %v", file, err)
+			}
+			res = append(res, v)
+		})
+		return res
+	}
+	names := func(v map[string]any) (res []string) {
+		for _, item := range v["itemListElement"].([]any) {
+			item := item.(map[string]any)
+			res = append(res, fmt.Sprintf("%v %v", item["name"], item["item"]))
+		}
+		return res
+	}
+
+	ld := scripts("posts/first/index.html")
+	if len(ld) != 1 || ld[0]["@context"] != "https://schema.org" || ld[0]["@type"] != "BreadcrumbList" {
+		t.Fatalf("unexpected JSON-LD: %v", ld)
+	}
+	want := []string{
+		"LD https://example.com/",
+		"Posts https://example.com/posts/",
+		"First https://example.com/posts/first/",
+	}
+	if got := names(ld[0]); !slices.Equal(got, want) {
+		t.Errorf("expected breadcrumbs %v, got %v", want, got)
+	}
+
+	ld = scripts("docs/guide/index.html")
+	if len(ld) != 2 {
+		t.Fatalf("expected 2 JSON-LD scripts, got %v", len(ld))
+	}
+	if ld[0]["@type"] != "Article" || ld[0]["headline"] != "</script><script>alert(1)</script>" {
+		t.Errorf("unexpected article: %v", ld[0])
+	}
+	want = []string{
+		"LD https://example.com/",
+		"docs https://example.com/docs/",
+		"Guide https://example.com/docs/guide/",
+	}
+	if got := names(ld[1]); !slices.Equal(got, want) {
+		t.Errorf("expected breadcrumbs %v, got %v", want, got)
+	}
+}
+
+func TestBaseConfig(t *testing.T) {
+	out := NewMemOutput()
+	g := NewGenerator(context.Background(), "Base", &GeneratorConfig{
+		Output: out,
+		BaseConfig: temfest.BaseConfig{
+			Lang:        "ar",
+			Dir:         "rtl",
+			HTMLClass:   "dark",
+			BodyAttrs:   templ.Attributes{"data-theme": "dark"},
+			ThemeColor:  "#000000",
+			ColorScheme: "dark",
+		},
+	})
+	g.AddRoute("/", testfest.Simple())
+	g.AddRoute("/en", testfest.Simple()).BaseConfig(temfest.BaseConfig{
+		Lang:      "en",
+		Dir:       "ltr",
+		BodyClass: "en",
+		Robots:    "noindex",
+		BaseHref:  "/en/",
+	})
+
+	if err := g.Generate(); err != nil {
+		t.Fatal(err)
+	}
+
+	data := []struct {
+		file, selector, attr, want string
+		found                      bool
+	}{
+		{"index.html", "html", "lang", "ar", true},
+		{"index.html", "html", "dir", "rtl", true},
+		{"index.html", "html", "class", "dark", true},
+		{"index.html", "body", "data-theme", "dark", true},
+		{"index.html", "body", "class", "", false},
+		{"index.html", `meta[name="theme-color"]`, "content", "#000000", true},
+		{"index.html", `meta[name="color-scheme"]`, "content", "dark", true},
+		{"index.html", `meta[name="robots"]`, "content", "", false},
+		{"index.html", "base", "href", "", false},
+		{"en/index.html", "html", "lang", "en", true},
+		{"en/index.html", "html", "dir", "ltr", true},
+		{"en/index.html", "html", "class", "dark", true},
+		{"en/index.html", "body", "data-theme", "dark", true},
+		{"en/index.html", "body", "class", "en", true},
+		{"en/index.html", `meta[name="theme-color"]`, "content", "#000000", true},
+		{"en/index.html", `meta[name="robots"]`, "content", "noindex", true},
+		{"en/index.html", "base", "href", "/en/", true},
+	}
+
+	for _, v := range data {
+		f, err := out.Open(v.file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		doc, err := goquery.NewDocumentFromReader(f)
+		f.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !v.found {
+			if val, ok := doc.Find(v.selector).Attr(v.attr); ok {
+				t.Errorf("%v: expected no %v on %v, found %v", v.file, v.attr, v.selector, val)
+			}
+			continue
+		}
+		if err := checkElAttr(nil, doc, v.selector, v.attr, v.want, true); err != nil {
+			t.Errorf("%v: %v", v.file, err)
+		}
+	}
+}
+
+func TestBaseConfigInheritance(t *testing.T) {
+	out := NewMemOutput()
+	global := temfest.BaseConfig{
+		Lang:       "en",
+		NoViewport: temfest.True,
+		HTMLAttrs:  templ.Attributes{"data-a": "g", "data-b": "g"},
+		Robots:     "noindex",
+	}
+	g := NewGenerator(context.Background(), "Inherit", &GeneratorConfig{Output: out, BaseConfig: global})
+	g.AddRoute("/", testfest.Simple())
+	g.AddRoute("/viewport", testfest.Simple()).BaseConfig(temfest.BaseConfig{
+		NoViewport: temfest.False,
+		HTMLAttrs:  templ.Attributes{"data-b": false},
+	})
+
+	NewRoutes("/items/{s}", []string{"first", "second"}).
+		BaseConfig(temfest.BaseConfig{Lang: "id", HTMLAttrs: templ.Attributes{"data-a": "rs"}}).
+		AddToGenerator(g, func(ctx context.Context, rp *RouteParam[string]) (templ.Component, error) {
+			if rp.GetSlug() == "first" {
+				rp.BaseConfig(temfest.BaseConfig{Dir: "rtl", NoViewport: temfest.False})
+				rp.HeadBody.Head(temfest.ImportStyle("/first.css"))
+			}
+			return testfest.Simple(), nil
+		})
+
+	if err := g.Generate(); err != nil {
+		t.Fatal(err)
+	}
+
+	data := []struct {
+		file, selector, attr, want string
+		found                      bool
+	}{
+		{"index.html", "html", "lang", "en", true},
+		{"index.html", "html", "data-a", "g", true},
+		{"index.html", "html", "data-b", "g", true},
+		{"index.html", `meta[name="viewport"]`, "content", "", false},
+		{"index.html", `meta[name="robots"]`, "content", "noindex", true},
+		{"viewport/index.html", `meta[name="viewport"]`, "content", "width=device-width, initial-scale=1.0", true},
+		{"viewport/index.html", "html", "data-a", "g", true},
+		{"viewport/index.html", "html", "data-b", "", false},
+		{"items/first/index.html", "html", "lang", "id", true},
+		{"items/first/index.html", "html", "dir", "rtl", true},
+		{"items/first/index.html", "html", "data-a", "rs", true},
+		{"items/first/index.html", "html", "data-b", "g", true},
+		{"items/first/index.html", `meta[name="viewport"]`, "content", "width=device-width, initial-scale=1.0", true},
+		{"items/first/index.html", `link[href="/first.css"]`, "rel", "stylesheet", true},
+		{"items/second/index.html", "html", "lang", "id", true},
+		{"items/second/index.html", "html", "dir", "", false},
+		{"items/second/index.html", `meta[name="viewport"]`, "content", "", false},
+		{"items/second/index.html", `meta[name="robots"]`, "content", "noindex", true},
+		{"items/second/index.html", `link[href="/first.css"]`, "rel", "", false},
+	}
+
+	for _, v := range data {
+		f, err := out.Open(v.file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		doc, err := goquery.NewDocumentFromReader(f)
+		f.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !v.found {
+			if val, ok := doc.Find(v.selector).Attr(v.attr); ok {
+				t.Errorf("%v: expected no %v on %v, found %v", v.file, v.attr, v.selector, val)
+			}
+			continue
+		}
+		if err := checkElAttr(nil, doc, v.selector, v.attr, v.want, true); err != nil {
+			t.Errorf("%v: %v", v.file, err)
+		}
+	}
+
+	if len(global.HTMLAttrs) != 2 || global.HTMLAttrs["data-b"] != "g" {
+		t.Errorf("parent attributes are modified: %v", global.HTMLAttrs)
+	}
+}
+
+func TestLayout(t *testing.T) {
+	text := func(s string) templ.Component {
+		return templ.ComponentFunc(func(ctx context.Context, w io.Writer) error {
+			_, err := io.WriteString(w, s)
+			return err
+		})
+	}
+	wrap := func(tag string, comps ...templ.Component) templ.Component {
+		return templ.ComponentFunc(func(ctx context.Context, w io.Writer) error {
+			io.WriteString(w, "<"+tag+">")
+			for _, c := range comps {
+				if err := c.Render(ctx, w); err != nil {
+					return err
+				}
+			}
+			_, err := io.WriteString(w, "</"+tag+">")
+			return err
+		})
+	}
+
+	out := NewMemOutput()
+	g := NewGenerator(context.Background(), "Layout", &GeneratorConfig{Output: out, NoBase: true})
+	g.AddLayout("site", func(lp *LayoutParam) templ.Component {
+		return wrap("div", wrap("header", lp.Slot("header")), lp.Main(), wrap("footer", text(lp.Path)))
+	})
+	g.NestLayout("docs", "site", func(lp *LayoutParam) templ.Component {
+		return wrap("section", wrap("h1", text(lp.Title)), wrap("aside", lp.Slot("sidebar")), wrap("main", lp.Main()))
+	})
+
+	g.AddRoute("/", text("home")).Layout("site").Slot("header", text("welcome"))
+	NewRoutes("/docs/{s}", []string{"intro", "usage"}).
+		Layout("docs").
+		Slot("sidebar", text("toc")).
+		AddToGenerator(g, func(ctx context.Context, rp *RouteParam[string]) (templ.Component, error) {
+			if rp.GetSlug() == "usage" {
+				rp.SetTitle("Usage")
+				rp.Slot("sidebar", text("usage toc"))
+			}
+			return text(rp.GetSlug()), nil
+		})
+
+	if err := g.Generate(); err != nil {
+		t.Fatal(err)
+	}
+
+	data := []struct{ file, want string }{
+		{"index.html", "<div><header>welcome</header>home<footer>/</footer></div>"},
+		{"docs/intro/index.html", "<div><header></header><section><h1>intro</h1><aside>toc</aside><main>intro</main></section><footer>/docs/intro</footer></div>"},
+		{"docs/usage/index.html", "<div><header></header><section><h1>Usage</h1><aside>usage toc</aside><main>usage</main></section><footer>/docs/usage</footer></div>"},
+	}
+	for _, v := range data {
+		b, err := fs.ReadFile(out, v.file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != v.want {
+			t.Errorf("%v: expected %v, got %v", v.file, v.want, string(b))
+		}
+	}
+
+	for _, name := range []string{"loop", "unknown"} {
+		g := NewGenerator(context.Background(), "Layout", &GeneratorConfig{Output: NewMemOutput()})
+		g.NestLayout("loop", "loop", func(lp *LayoutParam) templ.Component { return lp.Main() })
+		g.AddRoute("/", text("home")).Layout(name)
+		if err := g.Generate(); err == nil {
+			t.Errorf("expected error with layout %v", name)
+		}
+	}
+}
+
+func TestContext(t *testing.T) {
+	if GetTitle(context.Background()) != "" || GetPage(context.Background()) != 0 {
+		t.Error("expected zero values outside render context")
+	}
+
+	info := templ.ComponentFunc(func(ctx context.Context, w io.Writer) error {
+		_, err := fmt.Fprintf(w, "%v|%v|%v|%v|%v|%v|%v|%v",
+			GetTitle(ctx), GetPath(ctx), GetCanonicalURL(ctx), GetSiteName(ctx),
+			GetPage(ctx), GetBuildTime(ctx).Year(), GetEnv(ctx), GetData(ctx, "section"))
+		return err
+	})
+
+	t.Setenv("FEST_ENV", "staging")
+	out := NewMemOutput()
+	g := NewGenerator(context.Background(), "Ctx", &GeneratorConfig{
+		Output:    out,
+		NoBase:    true,
+		BaseURL:   "https://example.com",
+		Env:       "development",
+		BuildTime: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+	})
+	g.AddRoute("/about", info).SetTitle("About").SetData("section", "info")
+	NewPaginatedRoutes("/posts/{s}", []int{1, 2, 3}, 2).
+		SetData("section", "posts").
+		AddToGenerator(g, func(ctx context.Context, rp *RouteParam[*Pagination[int]]) (templ.Component, error) {
+			return info, nil
+		})
+
+	if err := g.Generate(); err != nil {
+		t.Fatal(err)
+	}
+
+	data := []struct{ file, want string }{
+		{"about/index.html", "About|/about|https://example.com/about/|Ctx|0|2024|staging|info"},
+		{"posts/1/index.html", "1|/posts/1|https://example.com/posts/1/|Ctx|1|2024|staging|posts"},
+		{"posts/2/index.html", "2|/posts/2|https://example.com/posts/2/|Ctx|2|2024|staging|posts"},
+	}
+	for _, v := range data {
+		b, err := fs.ReadFile(out, v.file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != v.want {
+			t.Errorf("%v: expected %v, got %v", v.file, v.want, string(b))
+		}
+	}
+}
+
+func TestNav(t *testing.T) {
+	sidebar := templ.ComponentFunc(func(ctx context.Context, w io.Writer) error {
+		cur := GetNavItem(ctx)
+		var s []string
+		for _, n := range cur.Section().Children {
+			s = append(s, ternary(n.IsActive(ctx), "*", "")+n.Title+" "+n.Path)
+		}
+		for _, n := range GetMenu(ctx, "main") {
+			s = append(s, ternary(n.HasActive(ctx), "menu*", "menu")+" "+n.Title)
+		}
+		s = append(s, fmt.Sprintf("siblings %v", len(cur.Siblings())))
+		_, err := io.WriteString(w, strings.Join(s, "|"))
+		return err
+	})
+
+	out := NewMemOutput()
+	g := NewGenerator(context.Background(), "Nav", &GeneratorConfig{Output: out, NoBase: true})
+	g.AddRoute("/", sidebar).Menu("main")
+	usage := g.AddRoute("/docs/usage", sidebar).SetTitle("Usage").SetWeight(2)
+	g.AddRoute("/docs", sidebar).SetTitle("Docs").Menu("main").SetWeight(1)
+	NewRoutes("/docs/{s}", []string{"install", "intro"}).
+		SetWeight(1).
+		AddToGenerator(g, func(ctx context.Context, rp *RouteParam[string]) (templ.Component, error) {
+			if rp.GetSlug() == "intro" {
+				rp.SetWeight(-1)
+			}
+			return sidebar, nil
+		})
+	g.AddRoute("/blog/first", sidebar).Menu("main")
+
+	if err := g.Generate(); err != nil {
+		t.Fatal(err)
+	}
+
+	data := []struct{ file, want string }{
+		{"docs/install/index.html", "intro /docs/intro/|*install /docs/install/|Usage /docs/usage/|menu* Nav|menu first|menu* Docs|siblings 2"},
+		{"docs/index.html", "intro /docs/intro/|install /docs/install/|Usage /docs/usage/|menu* Nav|menu first|menu* Docs|siblings 1"},
+		{"blog/first/index.html", "*first /blog/first/|menu* Nav|menu* first|menu Docs|siblings 0"},
+	}
+	for _, v := range data {
+		b, err := fs.ReadFile(out, v.file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != v.want {
+			t.Errorf("%v: expected %v, got %v", v.file, v.want, string(b))
+		}
+	}
+
+	// changing a route rebuilds the tree
+	usage.SetTitle("Guide").SetWeight(math.MinInt)
+	if err := g.Generate(); err != nil {
+		t.Fatal(err)
+	}
+	b, err := fs.ReadFile(out, "docs/index.html")
+	if want := "Guide /docs/usage/|intro /docs/intro/|install /docs/install/|menu* Nav|menu first|menu* Docs|siblings 1"; err != nil || string(b) != want {
+		t.Errorf("expected %v, got %s (%v)", want, b, err)
+	}
+
+	if GetNav(context.Background()) != nil {
+		t.Error("expected nil navigation outside render context")
+	}
+}
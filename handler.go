@@ -0,0 +1,267 @@
+package fest
+
+import (
+	"bytes"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"path"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Handler returns an http.Handler that serves the routes, copied files and
+// added files of g without writing anything to the Output. Routes are rendered on each
+// request the same way Generate renders them, and the headers set on g
+// and its routes are applied to the responses. Copied files are transformed
+// on each request as well, and files renamed by a Transformer are served
+// at their new path only.
+//
+// Paths that match nothing are answered with 404 status using the route or
+// copied file set in GeneratorConfig.NotFound, or a plain text response
+// if there is none.
+func (g *Generator) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodGet && req.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+		if err := g.routeErr(); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		p := path.Clean("/" + req.URL.Path)
+		if g.serve(w, req, p, http.StatusOK) {
+			return
+		}
+		if len(g.notFound) > 0 && g.serve(w, req, path.Clean("/"+g.notFound), http.StatusNotFound) {
+			return
+		}
+		http.NotFound(w, req)
+	})
+}
+
+// serve writes the route or copied file at the URL path p with status code.
+// It reports whether anything is found at p.
+func (g *Generator) serve(w http.ResponseWriter, req *http.Request, p string, code int) bool {
+	if r := g.findRoute(p); r != nil {
+		comp, ctx, err := g.page(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return true
+		}
+
+		var buf bytes.Buffer
+		if err := comp.Render(ctx, &buf); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return true
+		}
+
+		g.setHeaders(w, r.canonicalPath())
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.WriteHeader(code)
+		if req.Method != http.MethodHead {
+			w.Write(buf.Bytes())
+		}
+		return true
+	}
+
+	for _, v := range g.generated {
+		if "/"+v.dst != p {
+			continue
+		}
+
+		var buf bytes.Buffer
+		if err := v.fn(&buf); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return true
+		}
+		g.setHeaders(w, p)
+		if ctype := mime.TypeByExtension(path.Ext(p)); len(ctype) > 0 {
+			w.Header().Set("Content-Type", ctype)
+		}
+		w.WriteHeader(code)
+		if req.Method != http.MethodHead {
+			w.Write(buf.Bytes())
+		}
+		return true
+	}
+
+	src, c := g.findFile(p)
+	if c == nil {
+		return g.serveRenamed(w, req, p, code)
+	}
+	f, err := g.fsys.Open(src)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil || info.IsDir() {
+		return false
+	}
+
+	dst, r, err := c.transform(src, strings.TrimPrefix(p, "/"), f)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return true
+	}
+	// the file is renamed, so it's only available at its new path
+	if "/"+dst != p {
+		return g.serveRenamed(w, req, p, code)
+	}
+
+	g.setHeaders(w, p)
+	if code != http.StatusOK {
+		if ctype := mime.TypeByExtension(path.Ext(p)); len(ctype) > 0 {
+			w.Header().Set("Content-Type", ctype)
+		}
+		w.WriteHeader(code)
+		if req.Method != http.MethodHead {
+			_, _ = io.Copy(w, r)
+		}
+		return true
+	}
+
+	content, ok := r.(io.ReadSeeker)
+	if !ok {
+		b, err := io.ReadAll(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return true
+		}
+		content = bytes.NewReader(b)
+	}
+	http.ServeContent(w, req, info.Name(), info.ModTime(), content)
+	return true
+}
+
+// serveRenamed writes the copied file that is renamed to the URL path p by
+// a Transformer with status code. Since the new path is only known after
+// transforming, the assets with transformers are copied the same way
+// Generate copies them, keeping only the file at p.
+// It reports whether anything is found at p.
+func (g *Generator) serveRenamed(w http.ResponseWriter, req *http.Request, p string, code int) bool {
+	out := &captureOutput{name: strings.TrimPrefix(p, "/")}
+	for _, a := range slices.Concat(g.dirs, g.files) {
+		c, err := g.newCopier(a)
+		if err != nil || len(c.transforms) == 0 {
+			continue
+		}
+		c.out = out
+		if err := c.copy(a.src, a.target(), ".", nil); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return true
+		}
+	}
+	if !out.found {
+		return false
+	}
+
+	g.setHeaders(w, p)
+	if ctype := mime.TypeByExtension(path.Ext(p)); len(ctype) > 0 {
+		w.Header().Set("Content-Type", ctype)
+	}
+	w.WriteHeader(code)
+	if req.Method != http.MethodHead {
+		w.Write(out.buf.Bytes())
+	}
+	return true
+}
+
+// captureOutput is an Output that discards everything except the content
+// of the file name.
+type captureOutput struct {
+	name  string
+	found bool
+	buf   bytes.Buffer
+}
+
+func (o *captureOutput) Mkdir(string, fs.FileMode) error { return nil }
+
+func (o *captureOutput) Create(name string, _ fs.FileMode, _ time.Time) (io.WriteCloser, error) {
+	if name != o.name {
+		return nopWriteCloser{io.Discard}, nil
+	}
+	o.found = true
+	o.buf.Reset()
+	return nopWriteCloser{&o.buf}, nil
+}
+
+func (o *captureOutput) Symlink(string, string) error { return nil }
+
+func (o *captureOutput) Close() error { return nil }
+
+type nopWriteCloser struct{ io.Writer }
+
+func (nopWriteCloser) Close() error { return nil }
+
+// findRoute finds the route that is generated at the URL path p.
+func (g *Generator) findRoute(p string) *Route {
+	p = strings.TrimPrefix(p, "/")
+	if !strings.HasSuffix(p, ".html") || path.Base(p) == "index.html" {
+		p = strings.TrimSuffix(strings.TrimSuffix(p, "index.html"), "/")
+	}
+
+	for _, r := range g.routes {
+		if path.Clean("/"+r.path) == "/"+p {
+			return r
+		}
+	}
+	return nil
+}
+
+// findFile finds the source of the copied file at the URL path p
+// along with the copier of its Asset.
+func (g *Generator) findFile(p string) (string, *copier) {
+	p = strings.TrimPrefix(p, "/")
+
+	for _, v := range g.files {
+		if v.target() != p {
+			continue
+		}
+		c, err := g.newCopier(v)
+		if err != nil {
+			return "", nil
+		}
+		if src, ok := c.locate("."); ok {
+			return src, c
+		}
+		return "", nil
+	}
+	for _, v := range g.dirs {
+		rel := p
+		if dst := v.target(); dst != "." {
+			var ok bool
+			if rel, ok = strings.CutPrefix(p, dst+"/"); !ok {
+				continue
+			}
+		}
+
+		c, err := g.newCopier(v)
+		if err != nil {
+			continue
+		}
+		if src, ok := c.locate(rel); ok {
+			return src, c
+		}
+	}
+	return "", nil
+}
+
+// setHeaders sets the headers of all rules matching the URL path p.
+func (g *Generator) setHeaders(w http.ResponseWriter, p string) {
+	for _, rule := range g.headerRules() {
+		if !matchHeaderPattern(rule.pattern, p) {
+			continue
+		}
+		for k, v := range rule.header {
+			w.Header()[k] = v
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package fest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// HeadersFileOption specifies the format of the headers file written by Generate.
+type HeadersFileOption int
+
+const (
+	// Write a `_headers` file as used by Netlify and Cloudflare Pages.
+	HeadersFileNetlify HeadersFileOption = iota
+
+	// Write a `_headers.json` file containing a list of path and headers pairs.
+	HeadersFileJSON
+
+	// Don't write any headers file.
+	HeadersFileNone
+)
+
+// headerRule is a set of response headers for paths matching pattern.
+type headerRule struct {
+	pattern string
+	header  http.Header
+}
+
+// SetHeader sets a response header for every path matching pattern,
+// typically the copied assets. The pattern follows the `_headers` syntax
+// where `*` matches any sequence of characters, e.g. "/assets/*".
+func (g *Generator) SetHeader(pattern, key, value string) *Generator {
+	i := slices.IndexFunc(g.headers, func(h headerRule) bool { return h.pattern == pattern })
+	if i < 0 {
+		g.headers = append(g.headers, headerRule{pattern: pattern, header: http.Header{}})
+		i = len(g.headers) - 1
+	}
+	g.headers[i].header.Set(key, value)
+	return g
+}
+
+// headerRules returns the asset rules followed by the routes' own headers,
+// matched against the path the routes are served at after Generate.
+func (g *Generator) headerRules() []headerRule {
+	rules := slices.Clone(g.headers)
+	for _, r := range g.routes {
+		if len(r.header) > 0 {
+			rules = append(rules, headerRule{pattern: r.canonicalPath(), header: r.header})
+		}
+	}
+	return rules
+}
+
+// matchHeaderPattern reports whether the URL path p matches pattern,
+// where `*` matches any sequence of characters.
+func matchHeaderPattern(pattern, p string) bool {
+	parts := strings.Split(pattern, "*")
+	if len(parts) == 1 {
+		return pattern == p
+	}
+
+	rest, ok := strings.CutPrefix(p, parts[0])
+	if !ok {
+		return false
+	}
+	last := parts[len(parts)-1]
+	for _, part := range parts[1 : len(parts)-1] {
+		i := strings.Index(rest, part)
+		if i < 0 {
+			return false
+		}
+		rest = rest[i+len(part):]
+	}
+	return len(rest) >= len(last) && strings.HasSuffix(rest, last)
+}
+
+// writeHeaders writes rules to w in the format specified by opt.
+func writeHeaders(w io.Writer, rules []headerRule, opt HeadersFileOption) error {
+	switch opt {
+	case HeadersFileNetlify:
+		for i, rule := range rules {
+			if i > 0 {
+				if _, err := fmt.Fprintln(w); err != nil {
+					return err
+				}
+			}
+			if _, err := fmt.Fprintln(w, rule.pattern); err != nil {
+				return err
+			}
+			for _, k := range slices.Sorted(maps.Keys(rule.header)) {
+				for _, v := range rule.header[k] {
+					if _, err := fmt.Fprintf(w, "  %v: %v\n", k, v); err != nil {
+						return err
+					}
+				}
+			}
+		}
+		return nil
+	case HeadersFileJSON:
+		type entry struct {
+			Path    string      `json:"path"`
+			Headers http.Header `json:"headers"`
+		}
+		entries := make([]entry, 0, len(rules))
+		for _, rule := range rules {
+			entries = append(entries, entry{Path: rule.pattern, Headers: rule.header})
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(entries)
+	default:
+		return fmt.Errorf("unrecognized HeadersFileOption enum: %d", opt)
+	}
+}
+
+// headersFilename returns the name of the headers file for opt.
+func headersFilename(opt HeadersFileOption) string {
+	return ternary(opt == HeadersFileJSON, "_headers.json", "_headers")
+}
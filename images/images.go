@@ -0,0 +1,271 @@
+// Package images provides utilities used for generating responsive images.
+package images
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"io/fs"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/zilllaiss/fest"
+	"github.com/zilllaiss/fest/temfest"
+
+	"golang.org/x/image/draw"
+)
+
+// Config is the configuration for Processor.
+type Config struct {
+	// Widths of the generated variants in pixels. Widths that are not
+	// smaller than the source image's are skipped, as the source image
+	// itself is always included. By default it's 480, 960 and 1600.
+	Widths []int
+
+	// Quality is the JPEG quality of the variants. By default it's 85.
+	Quality int
+
+	// Sizes is the default sizes attribute of the rendered images.
+	// By default it's "100vw".
+	Sizes string
+
+	// Scaler is used to resize the images. By default it's draw.CatmullRom.
+	Scaler draw.Scaler
+}
+
+// Processor generates resized variants of JPEG and PNG images.
+type Processor struct {
+	fsys   fs.FS
+	config Config
+	images []*Image
+
+	mu      sync.Mutex
+	decoded struct {
+		src string
+		img image.Image
+	}
+}
+
+// New creates a new Processor reading images from fsys, typically
+// fest.Generator.SourceFS. Use nil to use default configs.
+func New(fsys fs.FS, config *Config) *Processor {
+	c := Config{}
+	if config != nil {
+		c = *config
+	}
+	if len(c.Widths) < 1 {
+		c.Widths = []int{480, 960, 1600}
+	}
+	if c.Quality == 0 {
+		c.Quality = 85
+	}
+	if len(c.Sizes) < 1 {
+		c.Sizes = "100vw"
+	}
+	if c.Scaler == nil {
+		c.Scaler = draw.CatmullRom
+	}
+	c.Widths = slices.Sorted(slices.Values(c.Widths))
+
+	return &Processor{fsys: fsys, config: c}
+}
+
+// Image is a source image with its variants.
+type Image struct {
+	// Src is the path of the source image in the filesystem.
+	Src string
+
+	// Width and Height are the size of the source image.
+	Width, Height int
+
+	// Variants are sorted by width, ending with the source image itself.
+	Variants []Variant
+
+	sizes string
+}
+
+// Variant is a resized version of an Image.
+type Variant struct {
+	// Path inside generated location.
+	Path string
+
+	// Width and Height are the size of the variant.
+	Width, Height int
+
+	// Type is the MIME type of the variant.
+	Type string
+}
+
+// URL returns the URL of the variant.
+func (v Variant) URL() string { return "/" + v.Path }
+
+// Temfest returns the image data used by temfest.Img and temfest.Picture.
+func (img *Image) Temfest(alt string) temfest.Image {
+	t := temfest.Image{Alt: alt, Width: img.Width, Height: img.Height, Sizes: img.sizes}
+	for _, v := range img.Variants {
+		t.Variants = append(t.Variants, temfest.ImageVariant{
+			URL: v.URL(), Width: v.Width, Height: v.Height, Type: v.Type,
+		})
+	}
+	if len(img.Variants) > 0 {
+		t.Src = img.Variants[len(img.Variants)-1].URL()
+	}
+	return t
+}
+
+// Add adds the image at src. Its variants will be generated inside dst directory,
+// named after the source with the width as suffix, e.g. "photo-480w.jpg".
+func (p *Processor) Add(src, dst string) (*Image, error) {
+	f, err := p.fsys.Open(src)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	conf, format, err := image.DecodeConfig(f)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding \"%v\": %w", src, err)
+	}
+	if format != "jpeg" && format != "png" {
+		return nil, fmt.Errorf("unsupported image format of \"%v\": %v", src, format)
+	}
+
+	dst = strings.Trim(path.Clean("/"+dst), "/")
+	name := path.Base(src)
+	ext := path.Ext(name)
+	name = strings.TrimSuffix(name, ext)
+
+	img := &Image{Src: src, Width: conf.Width, Height: conf.Height, sizes: p.config.Sizes}
+	for _, w := range p.config.Widths {
+		if w >= conf.Width {
+			break
+		}
+		img.Variants = append(img.Variants, Variant{
+			Path:   path.Join(dst, fmt.Sprintf("%v-%dw%v", name, w, ext)),
+			Width:  w,
+			Height: max(1, conf.Height*w/conf.Width),
+			Type:   "image/" + format,
+		})
+	}
+	img.Variants = append(img.Variants, Variant{
+		Path:   path.Join(dst, name+ext),
+		Width:  conf.Width,
+		Height: conf.Height,
+		Type:   "image/" + format,
+	})
+
+	p.images = append(p.images, img)
+	return img, nil
+}
+
+// AddDir adds all JPEG and PNG images inside dir recursively, keeping
+// their relative directories inside dst.
+func (p *Processor) AddDir(dir, dst string) ([]*Image, error) {
+	var imgs []*Image
+	err := fs.WalkDir(p.fsys, dir, func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		switch strings.ToLower(path.Ext(name)) {
+		case ".jpg", ".jpeg", ".png":
+		default:
+			return nil
+		}
+
+		rel := name
+		if dir != "." {
+			rel = strings.TrimPrefix(strings.TrimPrefix(name, dir), "/")
+		}
+		img, err := p.Add(name, path.Join(dst, path.Dir(rel)))
+		if err != nil {
+			return err
+		}
+		imgs = append(imgs, img)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return imgs, nil
+}
+
+// Get returns the added image from src, or nil if there is none.
+func (p *Processor) Get(src string) *Image {
+	for _, img := range p.images {
+		if img.Src == src {
+			return img
+		}
+	}
+	return nil
+}
+
+// AddToGenerator adds the variants of all added images to g.
+func (p *Processor) AddToGenerator(g *fest.Generator) {
+	for _, img := range p.images {
+		for i, v := range img.Variants {
+			if i == len(img.Variants)-1 {
+				g.AddFile(v.Path, func(w io.Writer) error { return p.copy(w, img) })
+				continue
+			}
+			g.AddFile(v.Path, func(w io.Writer) error { return p.resize(w, img, v) })
+		}
+	}
+}
+
+func (p *Processor) copy(w io.Writer, img *Image) error {
+	f, err := p.fsys.Open(img.Src)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = io.Copy(w, f)
+	return err
+}
+
+func (p *Processor) resize(w io.Writer, img *Image, v Variant) error {
+	src, err := p.decode(img.Src)
+	if err != nil {
+		return err
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, v.Width, v.Height))
+	p.config.Scaler.Scale(dst, dst.Bounds(), src, src.Bounds(), draw.Src, nil)
+
+	switch v.Type {
+	case "image/jpeg":
+		return jpeg.Encode(w, dst, &jpeg.Options{Quality: p.config.Quality})
+	case "image/png":
+		return png.Encode(w, dst)
+	}
+	return errors.New("unsupported image type: " + v.Type)
+}
+
+// decode decodes the image at src. The last decoded image is kept since
+// the variants of an image are generated one after another.
+func (p *Processor) decode(src string) (image.Image, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.decoded.src == src {
+		return p.decoded.img, nil
+	}
+
+	f, err := p.fsys.Open(src)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding \"%v\": %w", src, err)
+	}
+	p.decoded.src, p.decoded.img = src, img
+	return img, nil
+}
@@ -0,0 +1,89 @@
+package images
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"io/fs"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/zilllaiss/fest"
+	"github.com/zilllaiss/fest/temfest"
+)
+
+func TestProcessor(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 1200, 800))
+	for x := range 1200 {
+		for y := range 800 {
+			src.Set(x, y, color.RGBA{R: uint8(x), G: uint8(y), B: 128, A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, src, nil); err != nil {
+		t.Fatal(err)
+	}
+	fsys := fstest.MapFS{"content/img/2024/photo.jpg": {Data: buf.Bytes()}}
+
+	p := New(fsys, &Config{Widths: []int{960, 480, 1600}, Sizes: "50vw"})
+	imgs, err := p.AddDir("content/img", "img")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(imgs) != 1 || p.Get("content/img/2024/photo.jpg") != imgs[0] {
+		t.Fatalf("expected 1 image, found %v", imgs)
+	}
+
+	want := map[string]image.Point{
+		"img/2024/photo-480w.jpg": {480, 320},
+		"img/2024/photo-960w.jpg": {960, 640},
+		"img/2024/photo.jpg":      {1200, 800},
+	}
+
+	out := fest.NewMemOutput()
+	g := fest.NewGenerator(context.Background(), "images", &fest.GeneratorConfig{Output: out})
+	p.AddToGenerator(g)
+	if err := g.Generate(); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, size := range want {
+		f, err := out.Open(name)
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		conf, err := jpeg.DecodeConfig(f)
+		f.Close()
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		if conf.Width != size.X || conf.Height != size.Y {
+			t.Errorf("%v: expected %v, found %dx%d", name, size, conf.Width, conf.Height)
+		}
+	}
+	if _, err := fs.Stat(out, "img/2024/photo-1600w.jpg"); err == nil {
+		t.Error("expected no variant wider than the source")
+	}
+
+	var html strings.Builder
+	if err := temfest.Picture(imgs[0].Temfest("A photo"), nil).Render(context.Background(), &html); err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{
+		`srcset="/img/2024/photo-480w.jpg 480w, /img/2024/photo-960w.jpg 960w, /img/2024/photo.jpg 1200w"`,
+		`sizes="50vw"`,
+		`src="/img/2024/photo.jpg"`,
+		`width="1200" height="800"`,
+		`alt="A photo"`,
+		`<source type="image/jpeg"`,
+	} {
+		if !strings.Contains(html.String(), s) {
+			t.Errorf("expected %q in %v", s, html.String())
+		}
+	}
+}
@@ -0,0 +1,117 @@
+// Package glob matches slash-separated paths against glob patterns.
+package glob
+
+import (
+	"path"
+	"strings"
+)
+
+// Match reports whether name matches pattern. Besides the syntax of path.Match,
+// a `**` element matches zero or more path elements. A pattern without a slash
+// is matched against the last element of name, while a pattern with a leading
+// slash is anchored to the start of name.
+func Match(pattern, name string) bool {
+	anchored := strings.HasPrefix(pattern, "/")
+	pattern = strings.TrimPrefix(pattern, "/")
+	name = strings.TrimPrefix(name, "/")
+
+	if !anchored && !strings.Contains(pattern, "/") {
+		ok, _ := path.Match(pattern, path.Base(name))
+		return ok
+	}
+	return match(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+// MatchAny reports whether name matches any of patterns.
+func MatchAny(patterns []string, name string) bool {
+	for _, p := range patterns {
+		if Match(p, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func match(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			pattern = pattern[1:]
+			if len(pattern) == 0 {
+				return true
+			}
+			for i := range len(name) + 1 {
+				if match(pattern, name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], name[0]); !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
+
+// Ignore is a list of rules in the syntax of .gitignore files.
+type Ignore struct{ rules []ignoreRule }
+
+type ignoreRule struct {
+	pattern string
+	negate  bool
+	dirOnly bool
+}
+
+// ParseIgnore parses the content of a .gitignore-style file. Blank lines and
+// lines starting with `#` are skipped, `!` negates a pattern, and a trailing
+// slash only matches directories.
+func ParseIgnore(content string) *Ignore {
+	ig := &Ignore{}
+	for line := range strings.Lines(content) {
+		line = strings.TrimRight(line, "\r\n")
+		line = strings.TrimRight(line, " ")
+		if len(line) == 0 || line[0] == '#' {
+			continue
+		}
+
+		var r ignoreRule
+		if line[0] == '!' {
+			r.negate = true
+			line = line[1:]
+		} else if line[0] == '\\' {
+			line = line[1:]
+		}
+		if strings.HasSuffix(line, "/") {
+			r.dirOnly = true
+			line = strings.TrimSuffix(line, "/")
+		}
+		// like git, a slash in the middle anchors the pattern as well
+		if strings.Contains(line, "/") && !strings.HasPrefix(line, "**/") {
+			line = "/" + strings.TrimPrefix(line, "/")
+		}
+		r.pattern = line
+		ig.rules = append(ig.rules, r)
+	}
+	return ig
+}
+
+// Ignored reports whether name is ignored. The last matching rule wins.
+func (ig *Ignore) Ignored(name string, isDir bool) bool {
+	if ig == nil {
+		return false
+	}
+	var ignored bool
+	for _, r := range ig.rules {
+		if r.dirOnly && !isDir {
+			continue
+		}
+		if Match(r.pattern, name) {
+			ignored = !r.negate
+		}
+	}
+	return ignored
+}
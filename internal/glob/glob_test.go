@@ -0,0 +1,55 @@
+package glob
+
+import "testing"
+
+func TestMatch(t *testing.T) {
+	data := []struct {
+		pattern, name string
+		want          bool
+	}{
+		{"*.scss", "styles.scss", true},
+		{"*.scss", "nested/dir/styles.scss", true},
+		{"*.scss", "styles.css", false},
+		{"/*.scss", "nested/styles.scss", false},
+		{"/*.scss", "styles.scss", true},
+		{"nested/*.css", "nested/styles.css", true},
+		{"nested/*.css", "nested/deep/styles.css", false},
+		{"nested/**/*.css", "nested/styles.css", true},
+		{"nested/**/*.css", "nested/deep/er/styles.css", true},
+		{"**/fixtures", "a/b/fixtures", true},
+		{"fixtures/**", "fixtures", true},
+		{"fixtures/**", "fixtures/a/b.json", true},
+		{"fixtures/**", "other/a", false},
+	}
+	for _, v := range data {
+		if got := Match(v.pattern, v.name); got != v.want {
+			t.Errorf("Match(%q, %q): expected %t, found %t", v.pattern, v.name, v.want, got)
+		}
+	}
+}
+
+func TestIgnore(t *testing.T) {
+	ig := ParseIgnore("# comment\n.DS_Store\n*~\nbuild/\n*.log\n!keep.log\ndocs/*.md\n")
+
+	data := []struct {
+		name  string
+		isDir bool
+		want  bool
+	}{
+		{".DS_Store", false, true},
+		{"nested/.DS_Store", false, true},
+		{"styles.css~", false, true},
+		{"build", true, true},
+		{"build", false, false},
+		{"debug.log", false, true},
+		{"keep.log", false, false},
+		{"docs/a.md", false, true},
+		{"nested/docs/a.md", false, false},
+		{"styles.css", false, false},
+	}
+	for _, v := range data {
+		if got := ig.Ignored(v.name, v.isDir); got != v.want {
+			t.Errorf("Ignored(%q, %t): expected %t, found %t", v.name, v.isDir, v.want, got)
+		}
+	}
+}
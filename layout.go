@@ -0,0 +1,98 @@
+package fest
+
+import (
+	"fmt"
+	"maps"
+
+	"github.com/a-h/templ"
+	"github.com/zilllaiss/fest/temfest"
+)
+
+// SlotMain is the name of the slot filled with the route's component.
+const SlotMain = "main"
+
+// Layout renders the page of a route from its slots, e.g. "sidebar",
+// "header", "footer" and SlotMain.
+type Layout func(lp *LayoutParam) templ.Component
+
+type layout struct {
+	parent string
+	fn     Layout
+}
+
+// LayoutParam contains the slots and the metadata of the route being rendered.
+type LayoutParam struct {
+	// Title is the route's title without the site's name.
+	Title string
+
+	// Path is the URL path of the route.
+	Path string
+
+	// Meta is the route's page metadata with inherited and derived values.
+	Meta temfest.Meta
+
+	slots map[string]templ.Component
+}
+
+// Slot returns the component set for the slot name, or a no-op
+// component if there is none.
+func (lp *LayoutParam) Slot(name string) templ.Component {
+	if comp, ok := lp.slots[name]; ok {
+		return comp
+	}
+	return templ.NopComponent
+}
+
+// HasSlot reports whether the slot name is set.
+func (lp *LayoutParam) HasSlot(name string) bool {
+	_, ok := lp.slots[name]
+	return ok
+}
+
+// Main returns the main slot, that is the route's component or
+// the rendered nested layout.
+func (lp *LayoutParam) Main() templ.Component { return lp.Slot(SlotMain) }
+
+// AddLayout registers a layout with name that routes can select
+// with Route.Layout. It replaces the layout with the same name.
+func (g *Generator) AddLayout(name string, fn Layout) *Generator {
+	return g.NestLayout(name, "", fn)
+}
+
+// NestLayout registers a layout with name that is nested inside the parent
+// layout, i.e. the rendered layout becomes the main slot of the parent
+// while the other slots are passed as is.
+func (g *Generator) NestLayout(name, parent string, fn Layout) *Generator {
+	if g.layouts == nil {
+		g.layouts = map[string]layout{}
+	}
+	g.layouts[name] = layout{parent: parent, fn: fn}
+	return g
+}
+
+// applyLayout renders comp inside the layout selected by r and its parents.
+func (g *Generator) applyLayout(r *Route, comp templ.Component, title string, meta temfest.Meta) (templ.Component, error) {
+	slots := maps.Clone(r.slots)
+	if slots == nil {
+		slots = map[string]templ.Component{}
+	}
+	slots[SlotMain] = comp
+
+	seen := map[string]bool{}
+	for name := r.layout; len(name) > 0; {
+		l, ok := g.layouts[name]
+		if !ok {
+			return nil, fmt.Errorf("layout \"%v\" not found", name)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("layout \"%v\" is nested inside itself", name)
+		}
+		seen[name] = true
+
+		lp := &LayoutParam{Title: title, Path: r.urlPath(), Meta: meta, slots: slots}
+		slots = maps.Clone(slots)
+		slots[SlotMain] = l.fn(lp)
+		name = l.parent
+	}
+	return slots[SlotMain], nil
+}
@@ -0,0 +1,204 @@
+package markdown
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+var (
+	// ErrUnknownKey is the error of a frontmatter key that doesn't match any
+	// field with MarkdownProcessor.StrictFrontmatter. The keys recognized by
+	// MarkdownProcessor, such as "draft", are always known.
+	ErrUnknownKey = errors.New("unknown key")
+
+	// ErrMissingKey is the error of a field tagged with `fest:"required"`
+	// whose key isn't in the frontmatter.
+	ErrMissingKey = errors.New("missing required key")
+)
+
+// FrontmatterError is the error of decoding the frontmatter of a markdown file.
+type FrontmatterError struct {
+	// Path is the path of the file, or the slug of the markdown
+	// parsed with ParseBytes and ParseReader.
+	Path string
+
+	// Key is the offending frontmatter key. It's empty if the error
+	// isn't caused by a single key, e.g. a syntax error.
+	Key string
+
+	Err error
+}
+
+func (e *FrontmatterError) Error() string {
+	if len(e.Key) > 0 {
+		return fmt.Sprintf("error in frontmatter of \"%v\": key \"%v\": %v", e.Path, e.Key, e.Err)
+	}
+	return fmt.Sprintf("error in frontmatter of \"%v\": %v", e.Path, e.Err)
+}
+
+func (e *FrontmatterError) Unwrap() error { return e.Err }
+
+// Item is a parsed markdown file with its decoded frontmatter.
+type Item[T any] struct {
+	*MarkdownData
+
+	Frontmatter T
+}
+
+// ParseFilesAs parses all markdown files in dir with m.ParseFiles and decodes
+// their frontmatter into T, see MarkdownData.GetFrontmatter. Files without
+// frontmatter are decoded as empty frontmatter. The errors of all files
+// are joined together.
+func ParseFilesAs[T any](m MarkdownParser, dir string) ([]*Item[T], error) {
+	mds, err := m.ParseFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+	return decodeItems[T](mds)
+}
+
+// ParseFilesFSAs is like ParseFilesAs but reads the markdown files in dir from fsys.
+func ParseFilesFSAs[T any](m MarkdownParser, fsys fs.FS, dir string) ([]*Item[T], error) {
+	mds, err := m.ParseFilesFS(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+	return decodeItems[T](mds)
+}
+
+func decodeItems[T any](mds []*MarkdownData) ([]*Item[T], error) {
+	items := make([]*Item[T], 0, len(mds))
+	var errs []error
+	for _, md := range mds {
+		item := &Item[T]{MarkdownData: md}
+		if err := md.decodeFrontmatter(&item.Frontmatter); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		items = append(items, item)
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// decodeFrontmatter decodes the frontmatter into dst, treating missing
+// frontmatter as empty, and validates the keys if dst points to a struct.
+func (md *MarkdownData) decodeFrontmatter(dst any) error {
+	raw := map[string]any{}
+	if md.fm != nil {
+		if err := md.fm.Decode(dst); err != nil {
+			return &FrontmatterError{Path: md.name, Err: err}
+		}
+		if err := md.fm.Decode(&raw); err != nil {
+			return &FrontmatterError{Path: md.name, Err: err}
+		}
+	}
+
+	t := reflect.TypeOf(dst)
+	if t.Kind() != reflect.Pointer || t.Elem().Kind() != reflect.Struct {
+		return nil
+	}
+	fields := frontmatterFields(t.Elem())
+
+	for _, f := range fields {
+		if f.required && !f.in(raw, md.fmTOML) {
+			return &FrontmatterError{Path: md.name, Key: f.key(md.fmTOML), Err: ErrMissingKey}
+		}
+	}
+	if !md.strict {
+		return nil
+	}
+	for k := range raw {
+		known := slices.Contains(standardKeys, k)
+		for _, f := range fields {
+			if f.match(k, md.fmTOML) {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return &FrontmatterError{Path: md.name, Key: k, Err: ErrUnknownKey}
+		}
+	}
+	return nil
+}
+
+// frontmatterField is a struct field that can be decoded from frontmatter.
+type frontmatterField struct {
+	name, yaml, toml string
+	required         bool
+}
+
+// frontmatterFields returns the fields of the struct type t including the
+// fields of its embedded structs. Fields tagged with "-" are skipped.
+func frontmatterFields(t reflect.Type) []frontmatterField {
+	var fields []frontmatterField
+	for i := range t.NumField() {
+		sf := t.Field(i)
+		if !sf.IsExported() && !sf.Anonymous {
+			continue
+		}
+
+		f := frontmatterField{
+			name:     sf.Name,
+			yaml:     tagName(sf.Tag.Get("yaml")),
+			toml:     tagName(sf.Tag.Get("toml")),
+			required: sf.Tag.Get("fest") == "required",
+		}
+		if f.yaml == "-" || f.toml == "-" {
+			continue
+		}
+		if sf.Anonymous && sf.Type.Kind() == reflect.Struct && len(f.yaml) == 0 && len(f.toml) == 0 {
+			fields = append(fields, frontmatterFields(sf.Type)...)
+			continue
+		}
+		if sf.IsExported() {
+			fields = append(fields, f)
+		}
+	}
+	return fields
+}
+
+func tagName(tag string) string {
+	name, _, _ := strings.Cut(tag, ",")
+	return name
+}
+
+// match reports whether the frontmatter key k is decoded into f the same
+// way the decoders do it: yaml.v3 matches the tag, or the lowercased field
+// name without one, exactly, while TOML matches them case-insensitively.
+func (f frontmatterField) match(k string, toml bool) bool {
+	if toml {
+		return strings.EqualFold(k, f.key(toml))
+	}
+	return k == f.key(toml)
+}
+
+// in reports whether any key of raw is decoded into f.
+func (f frontmatterField) in(raw map[string]any, toml bool) bool {
+	for k := range raw {
+		if f.match(k, toml) {
+			return true
+		}
+	}
+	return false
+}
+
+// key returns the frontmatter key of f.
+func (f frontmatterField) key(toml bool) string {
+	switch {
+	case toml && len(f.toml) > 0:
+		return f.toml
+	case toml:
+		return f.name
+	case len(f.yaml) > 0:
+		return f.yaml
+	}
+	return strings.ToLower(f.name)
+}
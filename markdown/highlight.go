@@ -0,0 +1,68 @@
+package markdown
+
+import (
+	"io"
+
+	"github.com/zilllaiss/fest"
+
+	chromahtml "github.com/alecthomas/chroma/v2/formatters/html"
+	"github.com/alecthomas/chroma/v2/styles"
+	"github.com/yuin/goldmark"
+	highlighting "github.com/yuin/goldmark-highlighting/v2"
+)
+
+// HighlightConfig is the configuration for syntax highlighting, see WithHighlighting.
+type HighlightConfig struct {
+	// Style is the name of the chroma style, e.g. "monokai". Default "github".
+	Style string
+
+	// Classes renders CSS classes instead of inline styles. The stylesheet
+	// can be written with WriteStylesheet or AddStylesheet.
+	Classes bool
+
+	// LineNumbers renders line numbers for all code blocks. It can be
+	// set per code block with the fence attribute, e.g. {linenos=true}.
+	LineNumbers bool
+}
+
+// WithHighlighting returns an option for NewMarkdown that highlights fenced
+// code blocks with chroma. Lines can be highlighted with the fence
+// attributes, e.g. ```go {hl_lines=[2,3]}. Use nil to use default configs.
+func WithHighlighting(config *HighlightConfig) goldmark.Option {
+	c := config.withDefaults()
+	return goldmark.WithExtensions(highlighting.NewHighlighting(
+		highlighting.WithStyle(c.Style),
+		highlighting.WithFormatOptions(c.formatOptions()...),
+	))
+}
+
+// WriteStylesheet writes the stylesheet of the style for code blocks
+// rendered with Classes.
+func (c *HighlightConfig) WriteStylesheet(w io.Writer) error {
+	conf := c.withDefaults()
+	return chromahtml.New(conf.formatOptions()...).WriteCSS(w, styles.Get(conf.Style))
+}
+
+// AddStylesheet adds the stylesheet to dst inside g's generated location,
+// e.g. "css/highlight.css", see WriteStylesheet.
+func (c *HighlightConfig) AddStylesheet(g *fest.Generator, dst string) {
+	g.AddFile(dst, c.WriteStylesheet)
+}
+
+func (c *HighlightConfig) withDefaults() HighlightConfig {
+	var conf HighlightConfig
+	if c != nil {
+		conf = *c
+	}
+	if len(conf.Style) < 1 {
+		conf.Style = "github"
+	}
+	return conf
+}
+
+func (c HighlightConfig) formatOptions() []chromahtml.Option {
+	return []chromahtml.Option{
+		chromahtml.WithClasses(c.Classes),
+		chromahtml.WithLineNumbers(c.LineNumbers),
+	}
+}
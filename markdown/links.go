@@ -0,0 +1,159 @@
+package markdown
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/zilllaiss/fest"
+
+	"github.com/yuin/goldmark/ast"
+)
+
+// Asset is a file referenced by a relative link or image in markdown,
+// which is copied next to the rendered page, see MarkdownData.CopyAssets.
+type Asset struct {
+	// Src is the path of the file in the source the markdown is parsed
+	// from, e.g. "content/posts/img/diagram.png".
+	Src string
+
+	// Dst is the path of the file relative to the page's URL, e.g.
+	// "img/diagram.png". Files outside the markdown file's directory
+	// keep their path relative to the parsed directory instead.
+	Dst string
+}
+
+// CopyAssets copies the assets of md next to its page with g.CopyFile.
+// The assets are relative to g's source, thus md should be parsed from the
+// same source, e.g. with ParseFilesFS and fest.Generator.SourceFS.
+func (md *MarkdownData) CopyAssets(g *fest.Generator) {
+	for _, a := range md.Assets {
+		// CopyFile copies into the directory dst
+		g.CopyFile(a.Src, path.Dir(path.Join(md.URL, a.Dst)))
+	}
+}
+
+// pageLinks is used to rewrite the relative links of a markdown file.
+// The links and assets found are collected across rewrites, so the inner
+// content of shortcodes shares them with the rest of the file.
+type pageLinks struct {
+	fsys  fs.FS
+	dir   string
+	rel   string
+	files map[string]bool
+
+	links  []pageLink
+	assets []Asset
+}
+
+// pageLink is a rewritten link. Its destination is replaced with
+// a placeholder until the URLs of all pages are known.
+type pageLink struct {
+	// target is the path of the linked markdown file relative to the
+	// parsed directory. It's empty for assets.
+	target   string
+	fragment string
+	asset    string
+}
+
+var pageLinkRe = regexp.MustCompile(`fest-link:(\d+)`)
+
+// rewrite replaces the destination of relative links and images in doc with
+// placeholders, adding the links and assets found to lk.
+func (lk *pageLinks) rewrite(doc ast.Node) error {
+	var errs []error
+
+	ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+
+		var dest *[]byte
+		switch n := n.(type) {
+		case *ast.Link:
+			dest = &n.Destination
+		case *ast.Image:
+			dest = &n.Destination
+		default:
+			return ast.WalkContinue, nil
+		}
+
+		u, err := url.Parse(string(*dest))
+		if err != nil || len(u.Scheme) > 0 || len(u.Host) > 0 || len(u.Path) < 1 || strings.HasPrefix(u.Path, "/") {
+			return ast.WalkContinue, nil
+		}
+
+		fileDir := path.Dir(lk.rel)
+		target := path.Join(fileDir, u.Path)
+		if strings.HasPrefix(target, "../") || target == ".." {
+			errs = append(errs, fmt.Errorf("link \"%v\" is outside the parsed directory", string(*dest)))
+			return ast.WalkContinue, nil
+		}
+
+		link := pageLink{fragment: u.Fragment}
+		if lk.files[target] {
+			link.target = target
+		} else if info, err := fs.Stat(lk.fsys, path.Join(lk.dir, target)); err == nil && !info.IsDir() {
+			asset := Asset{Src: path.Join(lk.dir, target), Dst: strings.TrimPrefix(target, fileDir+"/")}
+			if i := slices.IndexFunc(lk.assets, func(a Asset) bool { return a.Dst == asset.Dst }); i < 0 {
+				lk.assets = append(lk.assets, asset)
+			} else if lk.assets[i].Src != asset.Src {
+				errs = append(errs, fmt.Errorf("link \"%v\" is copied to the same path as \"%v\"", string(*dest), lk.assets[i].Src))
+				return ast.WalkContinue, nil
+			}
+			link.asset = asset.Dst
+		} else {
+			errs = append(errs, fmt.Errorf("unresolvable link \"%v\"", string(*dest)))
+			return ast.WalkContinue, nil
+		}
+
+		*dest = []byte("fest-link:" + strconv.Itoa(len(lk.links)))
+		lk.links = append(lk.links, link)
+		return ast.WalkContinue, nil
+	})
+	return errors.Join(errs...)
+}
+
+// resolveLinks sets the URL of the pages and replaces the placeholders
+// in their content and image with the URLs of the linked pages and assets.
+func (m *MarkdownProcessor) resolveLinks(mds []*MarkdownData) error {
+	urls := map[string]string{}
+	for _, md := range mds {
+		md.URL = m.PageURL(md)
+		urls[md.RelPath] = md.URL
+	}
+
+	var errs []error
+	for _, md := range mds {
+		resolve := func(s string) string {
+			i, _ := strconv.Atoi(s[len("fest-link:"):])
+			if i >= len(md.links) {
+				return s
+			}
+
+			link := md.links[i]
+			if len(link.asset) > 0 {
+				return path.Join(md.URL, link.asset)
+			}
+			u, ok := urls[link.target]
+			if !ok {
+				errs = append(errs, fmt.Errorf("error in \"%v\": unresolvable link to unpublished \"%v\"", md.name, link.target))
+				return s
+			}
+			if len(link.fragment) > 0 {
+				u += "#" + link.fragment
+			}
+			return u
+		}
+		md.Content = pageLinkRe.ReplaceAllStringFunc(md.Content, resolve)
+		md.Image = pageLinkRe.ReplaceAllStringFunc(md.Image, resolve)
+		md.links = nil
+	}
+	return errors.Join(errs...)
+}
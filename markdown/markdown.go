@@ -5,9 +5,18 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
+	"io"
+	"io/fs"
 	"os"
+	"path"
 	"path/filepath"
+	"runtime"
+	"slices"
 	"strings"
+	"sync"
+	"time"
+
+	"github.com/zilllaiss/fest/internal/glob"
 
 	"github.com/yuin/goldmark"
 	"github.com/yuin/goldmark/parser"
@@ -22,6 +31,18 @@ type MarkdownParser interface {
 
 	// ParseFiles parses all markdown files in a path.
 	ParseFiles(path string) ([]*MarkdownData, error)
+
+	// ParseFileFS parses the markdown file name in fsys.
+	ParseFileFS(fsys fs.FS, name string) (*MarkdownData, error)
+
+	// ParseFilesFS parses all markdown files in dir of fsys.
+	ParseFilesFS(fsys fs.FS, dir string) ([]*MarkdownData, error)
+
+	// ParseBytes parses md with the explicitly set slug.
+	ParseBytes(slug string, md []byte) (*MarkdownData, error)
+
+	// ParseReader parses the markdown read from r with the explicitly set slug.
+	ParseReader(slug string, r io.Reader) (*MarkdownData, error)
 }
 
 // NewMarkdown initializes a new markdown parser with default configurations.
@@ -41,32 +62,156 @@ type MarkdownProcessor struct {
 	// The maximum depts of header that will be parsed. Default 3
 	TOCMaxDepth int
 
-	md goldmark.Markdown
+	// The minimum depth of header that will be parsed, e.g. 2 to skip
+	// the title of the page. By default there is no minimum.
+	TOCMinDepth int
+
+	// Recursive makes ParseFiles and ParseFilesFS parse the markdown files
+	// inside subdirectories too.
+	Recursive bool
+
+	// Include and Exclude are glob patterns matched against the path of
+	// markdown files relative to the parsed directory, e.g. "drafts/**" or
+	// "*.draft.md". Excluded directories are skipped entirely, and when
+	// Include is set, only the matching files are parsed.
+	Include, Exclude []string
+
+	// IndexAsSection treats index files, e.g. index.md, as the page of their
+	// directory, see MarkdownData.IsSection.
+	IndexAsSection bool
+
+	// SummaryWords is the number of words of MarkdownData.Summary when there
+	// is neither a summary frontmatter key nor `<!--more-->`. Default 70.
+	SummaryWords int
+
+	// WordsPerMinute is used to estimate MarkdownData.ReadingTime. Default 200.
+	WordsPerMinute int
+
+	// StrictFrontmatter rejects frontmatter keys that don't match any field of
+	// the struct it's decoded into, see MarkdownData.GetFrontmatter.
+	StrictFrontmatter bool
+
+	// Extensions are the accepted extensions of markdown files, e.g.
+	// ".markdown". By default it's only ".md".
+	Extensions []string
+
+	// Preview makes ParseFiles and ParseFilesFS include drafts, future and
+	// expired files, which are excluded by default. FEST_PREVIEW environment
+	// variable overrides it if set, e.g. FEST_PREVIEW=1.
+	Preview bool
+
+	// Now is the time used to check whether the files are published, see
+	// MarkdownData.IsPublished. By default it's the current time.
+	Now time.Time
+
+	// PageURL returns the URL path of the page generated from md, e.g.
+	// "/posts/first/". If set, ParseFiles and ParseFilesFS rewrite the
+	// relative links to other parsed markdown files to the URL of their
+	// page, and the relative links and images to other files to the URL
+	// they're copied to, see MarkdownData.Assets. Links that can't be
+	// resolved are errors.
+	PageURL func(md *MarkdownData) string
+
+	// Workers is the number of files ParseFiles and ParseFilesFS parse
+	// concurrently. By default it's runtime.GOMAXPROCS.
+	Workers int
+
+	md         goldmark.Markdown
+	shortcodes map[string]Shortcode
 }
 
 // ParseFile parses a markdown file in path.
 func (m *MarkdownProcessor) ParseFile(path string) (*MarkdownData, error) {
-	if m.TOCMaxDepth == 0 {
-		m.TOCMaxDepth = 3
-	}
-	ctx := parser.NewContext()
 	filename := filepath.Base(path)
 
-	if ext := filepath.Ext(filename); ext != ".md" {
-		return nil, fmt.Errorf("not an md file: %v", filename)
+	ext, ok := m.extension(filename)
+	if !ok {
+		return nil, fmt.Errorf("not a markdown file: %v", filename)
 	}
 
 	md, err := os.ReadFile(path)
 	if err != nil {
 		return nil, err
 	}
+	return m.parse(md, strings.TrimSuffix(filename, ext), path, nil)
+}
+
+// ParseFileFS is like ParseFile but reads the markdown file from fsys.
+func (m *MarkdownProcessor) ParseFileFS(fsys fs.FS, name string) (*MarkdownData, error) {
+	filename := path.Base(name)
+
+	ext, ok := m.extension(filename)
+	if !ok {
+		return nil, fmt.Errorf("not a markdown file: %v", filename)
+	}
+
+	md, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return nil, err
+	}
+	return m.parse(md, strings.TrimSuffix(filename, ext), name, nil)
+}
+
+// ParseBytes parses md with the explicitly set slug, e.g. markdown
+// that doesn't come from a file.
+func (m *MarkdownProcessor) ParseBytes(slug string, md []byte) (*MarkdownData, error) {
+	return m.parse(md, slug, slug, nil)
+}
+
+// ParseReader is like ParseBytes but reads the markdown from r.
+func (m *MarkdownProcessor) ParseReader(slug string, r io.Reader) (*MarkdownData, error) {
+	md, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("error reading markdown: %w", err)
+	}
+	return m.parse(md, slug, slug, nil)
+}
+
+// extension returns the accepted extension of the markdown file name.
+func (m *MarkdownProcessor) extension(name string) (string, bool) {
+	exts := m.Extensions
+	if len(exts) < 1 {
+		exts = []string{".md"}
+	}
+	for _, ext := range exts {
+		if len(name) > len(ext) && strings.HasSuffix(name, ext) {
+			return ext, true
+		}
+	}
+	return "", false
+}
+
+// parse parses md where name is the path of the file used in errors.
+// The relative links are rewritten with lk if it's not nil.
+func (m *MarkdownProcessor) parse(md []byte, slug, name string, lk *pageLinks) (*MarkdownData, error) {
+	tocMaxDepth := m.TOCMaxDepth
+	if tocMaxDepth == 0 {
+		tocMaxDepth = 3
+	}
+	ctx := parser.NewContext()
+
+	md, shortcodes, err := m.extractShortcodes(md, lk)
+	if err != nil {
+		return nil, fmt.Errorf("error in \"%v\": %w", name, err)
+	}
 
 	var mdBuf bytes.Buffer
 	var tocBuf bytes.Buffer
 
 	doc := m.md.Parser().Parse(text.NewReader(md), parser.WithContext(ctx))
 
-	tree, err := toc.Inspect(doc, md, toc.MaxDepth(m.TOCMaxDepth), toc.Compact(true))
+	var festMd MarkdownData
+	ptrFestMd := &festMd
+
+	if lk != nil {
+		if err := lk.rewrite(doc); err != nil {
+			return nil, fmt.Errorf("error in \"%v\": %w", name, err)
+		}
+		ptrFestMd.links, ptrFestMd.Assets = lk.links, lk.assets
+	}
+
+	tree, err := toc.Inspect(doc, md,
+		toc.MinDepth(m.TOCMinDepth), toc.MaxDepth(tocMaxDepth), toc.Compact(true))
 	if err != nil {
 		return nil, err
 	}
@@ -82,38 +227,181 @@ func (m *MarkdownProcessor) ParseFile(path string) (*MarkdownData, error) {
 		return nil, err
 	}
 
-	var festMd MarkdownData
-	ptrFestMd := &festMd
-
 	metaData := frontmatter.Get(ctx)
 	ptrFestMd.fm = metaData
+	ptrFestMd.fmTOML = metaData != nil && bytes.HasPrefix(bytes.TrimLeft(md, " \t"), []byte("+++"))
+	ptrFestMd.name = name
+	ptrFestMd.strict = m.StrictFrontmatter
 
-	ptrFestMd.Slug = strings.TrimSuffix(filename, ".md")
-	ptrFestMd.Content = mdBuf.String()
+	ptrFestMd.Slug = slug
+	ptrFestMd.Content = replaceShortcodes(mdBuf.String(), shortcodes)
 	ptrFestMd.TOC = tocBuf.String()
+	ptrFestMd.TOCTree = tocItems(tree.Items, headingLevels(doc))
+
+	if err := ptrFestMd.decodeStandard(); err != nil {
+		return nil, err
+	}
 
+	summaryWords := m.SummaryWords
+	if summaryWords < 1 {
+		summaryWords = 70
+	}
+	wordsPerMinute := m.WordsPerMinute
+	if wordsPerMinute < 1 {
+		wordsPerMinute = 200
+	}
+	ptrFestMd.inspect(doc, md, summaryWords, wordsPerMinute)
 	return ptrFestMd, nil
 }
 
-// ParseFiles parse all markdown files in path. Internally, this calls
-// ScanForMarkdown, ParseFile, and skip any nil MarkdownData.
-func (m *MarkdownProcessor) ParseFiles(path string) ([]*MarkdownData, error) {
-	f := []*MarkdownData{}
+// ParseFiles parse all markdown files in dir concurrently, skipping
+// subdirectories unless Recursive is set. The result is ordered by the
+// path of the files, and the errors of all files are joined together.
+// Unpublished files are excluded unless Preview is set.
+func (m *MarkdownProcessor) ParseFiles(dir string) ([]*MarkdownData, error) {
+	if len(dir) < 1 {
+		dir = "."
+	}
+	f, err := m.parseFiles(os.DirFS(dir), ".")
+	if err != nil {
+		return nil, fmt.Errorf("error parsing markdown in \"%v\": %w", dir, err)
+	}
+	for _, md := range f {
+		for i, a := range md.Assets {
+			md.Assets[i].Src = filepath.Join(dir, filepath.FromSlash(a.Src))
+		}
+	}
+	return f, nil
+}
+
+// ParseFilesFS is like ParseFiles but reads the markdown files in dir from fsys.
+func (m *MarkdownProcessor) ParseFilesFS(fsys fs.FS, dir string) ([]*MarkdownData, error) {
+	return m.parseFiles(fsys, dir)
+}
 
-	if err := ScanForMarkdown(path, func(p string) error {
-		festMd, err := m.ParseFile(p)
+func (m *MarkdownProcessor) parseFiles(fsys fs.FS, dir string) ([]*MarkdownData, error) {
+	files, err := m.scan(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+	slices.Sort(files)
+
+	workers := m.Workers
+	if workers < 1 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+
+	var known map[string]bool
+	if m.PageURL != nil {
+		known = make(map[string]bool, len(files))
+		for _, rel := range files {
+			known[rel] = true
+		}
+	}
+
+	f := make([]*MarkdownData, len(files))
+	errs := make([]error, len(files))
+	indexes := make(chan int)
+
+	var wg sync.WaitGroup
+	for range min(workers, len(files)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				f[i], errs[i] = m.parseRel(fsys, dir, files[i], known)
+			}
+		}()
+	}
+	for i := range files {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	if !m.preview() {
+		now := m.now()
+		f = slices.DeleteFunc(f, func(md *MarkdownData) bool { return !md.IsPublished(now) })
+	}
+	if m.PageURL != nil {
+		if err := m.resolveLinks(f); err != nil {
+			return nil, err
+		}
+	}
+	return f, nil
+}
+
+// parseRel parses the markdown file at rel inside dir. The relative links
+// are rewritten if known, the set of all parsed files, is not nil.
+func (m *MarkdownProcessor) parseRel(fsys fs.FS, dir, rel string, known map[string]bool) (*MarkdownData, error) {
+	var lk *pageLinks
+	if known != nil {
+		lk = &pageLinks{fsys: fsys, dir: dir, rel: rel, files: known}
+	}
+
+	src, err := fs.ReadFile(fsys, path.Join(dir, rel))
+	if err != nil {
+		return nil, fmt.Errorf("error parsing \"%v\": %w", rel, err)
+	}
+	ext, _ := m.extension(rel)
+	festMd, err := m.parse(src, strings.TrimSuffix(path.Base(rel), ext), path.Join(dir, rel), lk)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing \"%v\": %w", rel, err)
+	}
+	festMd.RelPath = rel
+	festMd.Section = strings.TrimPrefix(path.Dir(rel), ".")
+	festMd.IsSection = m.IndexAsSection && festMd.Slug == "index"
+	return festMd, nil
+}
+
+// scan returns the paths of markdown files inside dir relative to it.
+func (m *MarkdownProcessor) scan(fsys fs.FS, dir string) ([]string, error) {
+	var files []string
+	err := fs.WalkDir(fsys, dir, func(p string, d fs.DirEntry, err error) error {
 		if err != nil {
 			return err
 		}
-		if festMd != nil {
-			f = append(f, festMd)
+		if p == dir {
+			return nil
 		}
+
+		rel := p
+		if dir != "." {
+			rel = strings.TrimPrefix(p, dir+"/")
+		}
+		if d.IsDir() {
+			if !m.Recursive || glob.MatchAny(m.Exclude, rel) {
+				return fs.SkipDir
+			}
+			return nil
+		}
+
+		if _, ok := m.extension(rel); !ok || glob.MatchAny(m.Exclude, rel) {
+			return nil
+		}
+		if len(m.Include) > 0 && !glob.MatchAny(m.Include, rel) {
+			return nil
+		}
+		files = append(files, rel)
 		return nil
-	}); err != nil {
-		return nil, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error scanning for markdown: %w", err)
 	}
+	return files, nil
+}
 
-	return f, nil
+// Path returns the path of the page relative to the directory passed to
+// ParseFiles without the extension, i.e. Section for section pages and
+// Section joined with Slug for others, e.g. "2024/foo".
+func (md *MarkdownData) Path() string {
+	if md.IsSection {
+		return md.Section
+	}
+	return path.Join(md.Section, md.Slug)
 }
 
 // Unwrap returns the original goldmark.Markdown.
@@ -121,19 +409,73 @@ func (m *MarkdownProcessor) Unwrap() goldmark.Markdown { return m.md }
 
 // MarkdownData contains all data parsed from the markdown file.
 type MarkdownData struct {
-	// Filename excluding the .md extension.
+	// Filename excluding the extension, or the slug passed to ParseBytes
+	// and ParseReader.
 	Slug string
 
+	// Section is the slash-separated directory of the file relative to the
+	// directory passed to ParseFiles, e.g. "2024" for "posts/2024/foo.md"
+	// parsed from "posts". It's empty for files at the top.
+	Section string
+
+	// RelPath is the slash-separated path of the file relative to
+	// the directory passed to ParseFiles, e.g. "2024/foo.md".
+	RelPath string
+
+	// URL is the URL path of the page set by MarkdownProcessor.PageURL.
+	URL string
+
+	// Assets are the files referenced by relative links and images with
+	// MarkdownProcessor.PageURL set.
+	Assets []Asset
+
+	// IsSection reports whether the file is the index.md of its
+	// Section with MarkdownProcessor.IndexAsSection set.
+	IsSection bool
+
+	// Draft, Date, PublishDate and ExpiryDate are decoded from the frontmatter
+	// keys of the same name in camel case, e.g. "publishDate". Date is left
+	// unset if it's not a date, e.g. a free-form string, while the other
+	// keys fail the parsing if they don't convert.
+	Draft       bool
+	Date        time.Time
+	PublishDate time.Time
+	ExpiryDate  time.Time
+
 	// Main content excluding the frontmatter.
 	Content string
 
 	// Table of Content parsed from headers.
 	TOC string
 
-	fm *frontmatter.Data
+	// TOCTree is the structured TOC, e.g. for rendering it with a component.
+	TOCTree []*TOCItem
+
+	// Summary is the plain text summary of the content. It's the summary
+	// frontmatter key if it's a string, the content before `<!--more-->`,
+	// or the first words of the content, whichever comes first.
+	Summary string
+
+	// WordCount is the number of words in the content excluding code blocks.
+	WordCount int
+
+	// ReadingTime is the estimated reading time rounded up to minutes.
+	ReadingTime time.Duration
+
+	// Image is the URL of the first image in the content, if any.
+	Image string
+
+	fm     *frontmatter.Data
+	fmTOML bool
+	name   string
+	strict bool
+	links  []pageLink
 }
 
 // GetFrontmatter decodes the frontmatter data. This will panic if data is not a pointer type.
+// When data is a pointer to a struct, its fields tagged with `fest:"required"` must
+// be set, and keys without a field are rejected with MarkdownProcessor.StrictFrontmatter.
+// Such errors are *FrontmatterError.
 //
 // Be sure to have the frontmatter seperators (i.e. - or +) to be equal in amount
 // for both sides, and don't have leading spaces, as it is one of the most
@@ -142,18 +484,31 @@ func (md *MarkdownData) GetFrontmatter(data any) error {
 	if md.fm == nil {
 		return errors.New("frontmatter not found")
 	}
-	if err := md.fm.Decode(data); err != nil {
-		return err
-	}
-	return nil
+	return md.decodeFrontmatter(data)
 }
 
 // ScanForMarkdown scan a path for markdown files and pass the path and the filename
 // to fn as p. If there is an error, it will be os.ReadDir error or an error from
 // fn
 func ScanForMarkdown(path string, fn func(p string) error) error {
+	return scanForMarkdown(os.ReadDir, filepath.Join, path, fn)
+}
+
+// ScanForMarkdownFS is like ScanForMarkdown but scans dir in fsys.
+// The path passed to fn is a slash-separated path in fsys.
+func ScanForMarkdownFS(fsys fs.FS, dir string, fn func(p string) error) error {
+	readDir := func(name string) ([]fs.DirEntry, error) { return fs.ReadDir(fsys, name) }
+	return scanForMarkdown(readDir, path.Join, dir, fn)
+}
+
+func scanForMarkdown(
+	readDir func(string) ([]fs.DirEntry, error),
+	join func(...string) string,
+	dir string,
+	fn func(p string) error,
+) error {
 	msg := "error scanning for markdown"
-	mdDir, err := os.ReadDir(path)
+	mdDir, err := readDir(dir)
 	if err != nil {
 		return fmt.Errorf("%v: %w", msg, err)
 	}
@@ -162,7 +517,7 @@ func ScanForMarkdown(path string, fn func(p string) error) error {
 		if ext := filepath.Ext(content.Name()); content.IsDir() || ext != ".md" {
 			continue
 		}
-		p := filepath.Join(path, content.Name())
+		p := join(dir, content.Name())
 
 		if err := fn(p); err != nil {
 			return fmt.Errorf("%v: %w", msg, err)
@@ -0,0 +1,606 @@
+package markdown
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/a-h/templ"
+	"github.com/zilllaiss/fest"
+)
+
+func TestParseFilesRecursive(t *testing.T) {
+	fsys := fstest.MapFS{
+		"content/posts/index.md":           {Data: []byte("# Posts")},
+		"content/posts/first.md":           {Data: []byte("# First")},
+		"content/posts/2024.md":            {Data: []byte("# Archive")},
+		"content/posts/2024/second.md":     {Data: []byte("# Second")},
+		"content/posts/2024/index.md":      {Data: []byte("# 2024")},
+		"content/posts/2024/notes.txt":     {Data: []byte("notes")},
+		"content/posts/drafts/third.md":    {Data: []byte("# Third")},
+		"content/posts/2025/fourth.wip.md": {Data: []byte("# Fourth")},
+		"content/posts/2025/deep/fifth.md": {Data: []byte("# Fifth")},
+	}
+
+	data := []struct {
+		name string
+		conf MarkdownProcessor
+		want []string
+	}{
+		{
+			name: "flat",
+			want: []string{"2024.md  2024 false", "first.md  first false", "index.md  index false"},
+		},
+		{
+			name: "recursive",
+			conf: MarkdownProcessor{Recursive: true, Exclude: []string{"drafts", "*.wip.md"}},
+			want: []string{
+				"2024.md  2024 false",
+				"2024/index.md 2024 2024/index false",
+				"2024/second.md 2024 2024/second false",
+				"2025/deep/fifth.md 2025/deep 2025/deep/fifth false",
+				"first.md  first false",
+				"index.md  index false",
+			},
+		},
+		{
+			name: "sections",
+			conf: MarkdownProcessor{Recursive: true, IndexAsSection: true, Include: []string{"index.md"}},
+			want: []string{"2024/index.md 2024 2024 true", "index.md   true"},
+		},
+	}
+
+	for _, v := range data {
+		t.Run(v.name, func(t *testing.T) {
+			m := NewMarkdown().(*MarkdownProcessor)
+			m.Recursive, m.Include, m.Exclude = v.conf.Recursive, v.conf.Include, v.conf.Exclude
+			m.IndexAsSection = v.conf.IndexAsSection
+
+			mds, err := m.ParseFilesFS(fsys, "content/posts")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var got []string
+			for _, md := range mds {
+				got = append(got, md.RelPath+" "+md.Section+" "+md.Path()+" "+strconv.FormatBool(md.IsSection))
+			}
+			if !slices.Equal(got, v.want) {
+				t.Errorf("expected %q, got %q", v.want, got)
+			}
+		})
+	}
+}
+
+// failFS fails to open the files in fail.
+type failFS struct {
+	fsys fstest.MapFS
+	fail []string
+}
+
+func (f failFS) Open(name string) (fs.File, error) {
+	if slices.Contains(f.fail, name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrPermission}
+	}
+	return f.fsys.Open(name)
+}
+
+func TestParseFilesConcurrent(t *testing.T) {
+	fsys := fstest.MapFS{}
+	var want []string
+	for i := range 50 {
+		name := fmt.Sprintf("%02d.md", i)
+		fsys[name] = &fstest.MapFile{Data: fmt.Appendf(nil, "# Title %d", i)}
+		want = append(want, strings.TrimSuffix(name, ".md"))
+	}
+
+	m := NewMarkdown().(*MarkdownProcessor)
+	m.Workers = 4
+
+	mds, err := m.ParseFilesFS(fsys, ".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, md := range mds {
+		got = append(got, md.Slug)
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+
+	_, err = m.ParseFilesFS(failFS{fsys, []string{"03.md", "42.md"}}, ".")
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	for _, name := range []string{"03.md", "42.md"} {
+		if !strings.Contains(err.Error(), name) {
+			t.Errorf("expected error of %v, got %v", name, err)
+		}
+	}
+	if !errors.Is(err, fs.ErrPermission) {
+		t.Errorf("expected fs.ErrPermission, got %v", err)
+	}
+}
+
+func TestParseSources(t *testing.T) {
+	m := NewMarkdown().(*MarkdownProcessor)
+
+	md, err := m.ParseBytes("from-cms", []byte("# Hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if md.Slug != "from-cms" || !strings.Contains(md.Content, "Hello</h1>") {
+		t.Errorf("unexpected result: %v %v", md.Slug, md.Content)
+	}
+
+	md, err = m.ParseReader("reader", strings.NewReader("---\ntitle: Reader\n---\n# Hi"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fm struct{ Title string }
+	if err := md.GetFrontmatter(&fm); err != nil {
+		t.Fatal(err)
+	}
+	if md.Slug != "reader" || fm.Title != "Reader" {
+		t.Errorf("unexpected result: %v %v", md.Slug, fm.Title)
+	}
+
+	fsys := fstest.MapFS{
+		"a.md":       {Data: []byte("# A")},
+		"b.markdown": {Data: []byte("# B")},
+		"c.mdx-lite": {Data: []byte("# C")},
+		"d.txt":      {Data: []byte("D")},
+		".markdown":  {Data: []byte("# none")},
+	}
+	if _, err := m.ParseFileFS(fsys, "b.markdown"); err == nil {
+		t.Error("expected error parsing .markdown by default")
+	}
+
+	m.Extensions = []string{".markdown", ".mdx-lite"}
+	mds, err := m.ParseFilesFS(fsys, ".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, md := range mds {
+		got = append(got, md.Slug)
+	}
+	if want := []string{"b", "c"}; !slices.Equal(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestParseFilesAs(t *testing.T) {
+	type base struct {
+		Title string `yaml:"title" fest:"required"`
+	}
+	type post struct {
+		base `yaml:",inline"`
+
+		Published time.Time `yaml:"published"`
+		Tags      []string
+		Internal  string `yaml:"-"`
+	}
+
+	fsys := fstest.MapFS{
+		"posts/a.md": {Data: []byte("---\ntitle: A\npublished: 2024-01-02T00:00:00Z\ntags: [go]\n---\n# A")},
+		"posts/b.md": {Data: []byte("---\ntitle: B\npublised: 2024-01-02T00:00:00Z\n---\n# B")},
+		"posts/c.md": {Data: []byte("# C")},
+	}
+
+	m := NewMarkdown().(*MarkdownProcessor)
+	_, err := ParseFilesFSAs[post](m, fsys, "posts")
+
+	var fmErr *FrontmatterError
+	if !errors.As(err, &fmErr) || fmErr.Path != "posts/c.md" || fmErr.Key != "title" || !errors.Is(err, ErrMissingKey) {
+		t.Fatalf("expected missing title in posts/c.md, got %v", err)
+	}
+
+	delete(fsys, "posts/c.md")
+	items, err := ParseFilesFSAs[post](m, fsys, "posts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 2 || items[0].Frontmatter.Title != "A" || items[0].Frontmatter.Published.Year() != 2024 ||
+		!slices.Equal(items[0].Frontmatter.Tags, []string{"go"}) || items[1].Slug != "b" {
+		t.Errorf("unexpected items: %+v %+v", items[0], items[1])
+	}
+
+	m.StrictFrontmatter = true
+	_, err = ParseFilesFSAs[post](m, fsys, "posts")
+	if !errors.As(err, &fmErr) || fmErr.Path != "posts/b.md" || fmErr.Key != "publised" || !errors.Is(err, ErrUnknownKey) {
+		t.Fatalf("expected unknown publised in posts/b.md, got %v", err)
+	}
+	if !strings.Contains(err.Error(), `"posts/b.md"`) || !strings.Contains(err.Error(), `"publised"`) {
+		t.Errorf("expected path and key in error, got %v", err)
+	}
+
+	// YAML keys must match exactly the way yaml.v3 decodes them, TOML keys
+	// are matched case-insensitively
+	for src, want := range map[string]error{
+		"---\nTITLE: D\n---\n":                       ErrMissingKey,
+		"---\ntitle: D\nTags: [go]\n---\n":           ErrUnknownKey,
+		"+++\nTITLE = \"D\"\nTags = [\"go\"]\n+++\n": nil,
+	} {
+		fsys := fstest.MapFS{"d.md": {Data: []byte(src)}}
+		items, err := ParseFilesFSAs[post](m, fsys, ".")
+		if !errors.Is(err, want) || (want == nil && items[0].Frontmatter.Title != "D") {
+			t.Errorf("%q: expected %v, got %v", src, want, err)
+		}
+	}
+}
+
+func TestPublishing(t *testing.T) {
+	fsys := fstest.MapFS{
+		"draft.md":    {Data: []byte("---\ndraft: true\n---\n# Draft")},
+		"expired.md":  {Data: []byte("---\ndate: 2023-01-01\nexpiryDate: 2024-01-01\n---\n# Expired")},
+		"future.md":   {Data: []byte("---\ndate: 2023-01-01\npublishDate: 2025-01-01\n---\n# Future")},
+		"freeform.md": {Data: []byte("---\ndate: last tuesday\n---\n# Free-form")},
+		"past.md":     {Data: []byte("---\ndate: 2023-01-01\n---\n# Past")},
+		"plain.md":    {Data: []byte("# Plain")},
+		"toml.md":     {Data: []byte("+++\ndate = 2026-01-01\n+++\n# TOML")},
+		"upcoming.md": {Data: []byte("---\ndate: 2024-07-01\n---\n# Upcoming")},
+	}
+
+	m := NewMarkdown().(*MarkdownProcessor)
+	m.Now = time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	m.StrictFrontmatter = true
+
+	slugs := func() []string {
+		mds, err := m.ParseFilesFS(fsys, ".")
+		if err != nil {
+			t.Fatal(err)
+		}
+		var res []string
+		for _, md := range mds {
+			res = append(res, md.Slug)
+		}
+		return res
+	}
+
+	if got, want := slugs(), []string{"freeform", "past", "plain"}; !slices.Equal(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+
+	all := []string{"draft", "expired", "freeform", "future", "past", "plain", "toml", "upcoming"}
+	m.Preview = true
+	if got := slugs(); !slices.Equal(got, all) {
+		t.Errorf("expected %v with preview, got %v", all, got)
+	}
+
+	m.Preview = false
+	t.Setenv("FEST_PREVIEW", "true")
+	if got := slugs(); !slices.Equal(got, all) {
+		t.Errorf("expected %v with FEST_PREVIEW, got %v", all, got)
+	}
+
+	md, err := m.ParseFileFS(fsys, "future.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if md.Date.Year() != 2023 || md.PublishDate.Year() != 2025 || md.IsPublished(m.Now) {
+		t.Errorf("unexpected dates: %v %v", md.Date, md.PublishDate)
+	}
+
+	var fm struct{ Title string }
+	if err := md.GetFrontmatter(&fm); err != nil {
+		t.Errorf("expected standard keys to be known in strict mode, got %v", err)
+	}
+
+	// broken publishing keys fail the file instead of publishing it
+	for name, src := range map[string]string{
+		"quoted draft": "---\ndraft: \"true\"\n---\n# Draft",
+		"partial date": "---\npublishDate: 2030-01-01 10:00\n---\n# Future",
+		"syntax error": "---\ndraft: true\ntitle: foo: bar\n---\n# Draft",
+	} {
+		fsys := fstest.MapFS{"broken.md": {Data: []byte(src)}}
+		var fmErr *FrontmatterError
+		if _, err := m.ParseFilesFS(fsys, "."); !errors.As(err, &fmErr) || fmErr.Path != "broken.md" {
+			t.Errorf("%v: expected frontmatter error, got %v", name, err)
+		}
+	}
+}
+
+func TestTOCTree(t *testing.T) {
+	src := "# Title\n\n## Install\n\n### From source\n\n#### Deep\n\n## Usage `code`\n"
+
+	m := NewMarkdown().(*MarkdownProcessor)
+	m.TOCMinDepth = 2
+
+	md, err := m.ParseBytes("toc", []byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	var walk func(items []*TOCItem)
+	walk = func(items []*TOCItem) {
+		for _, v := range items {
+			got = append(got, fmt.Sprintf("%d %v #%v", v.Depth, v.Title, v.ID))
+			walk(v.Children)
+		}
+	}
+	walk(md.TOCTree)
+
+	want := []string{"2 Install #install", "3 From source #from-source", "2 Usage code #usage-code"}
+	if !slices.Equal(got, want) {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+	if strings.Contains(md.TOC, "#title") || !strings.Contains(md.TOC, "#install") {
+		t.Errorf("unexpected TOC HTML: %v", md.TOC)
+	}
+}
+
+func TestSummary(t *testing.T) {
+	m := NewMarkdown().(*MarkdownProcessor)
+	m.SummaryWords = 5
+	m.WordsPerMinute = 10
+
+	long := strings.Repeat("word ", 25)
+	data := []struct {
+		name, src, summary string
+		words              int
+		reading            time.Duration
+		image              string
+	}{
+		{
+			name:    "more",
+			src:     "# Hello\n\nThe *first* paragraph.\n\n<!--more-->\n\n![cover](/img/cover.png)\n\n```go\nfunc main() {}\n```\n\nThe end.",
+			summary: "Hello The first paragraph.",
+			words:   6,
+			reading: time.Minute,
+			image:   "/img/cover.png",
+		},
+		{
+			name:    "frontmatter",
+			src:     "---\nsummary: From frontmatter\n---\n" + long,
+			summary: "From frontmatter",
+			words:   25,
+			reading: 3 * time.Minute,
+		},
+		{
+			name:    "non-string frontmatter",
+			src:     "---\nsummary:\n  - a\n  - b\n---\nOne two three four five six",
+			summary: "One two three four five",
+			words:   6,
+			reading: time.Minute,
+		},
+		{
+			name:    "words",
+			src:     "Some `inline code` and\na [link](/x) here, then more.",
+			summary: "Some inline code and a",
+			words:   9,
+			reading: time.Minute,
+		},
+		{name: "empty"},
+	}
+
+	for _, v := range data {
+		t.Run(v.name, func(t *testing.T) {
+			md, err := m.ParseBytes(v.name, []byte(v.src))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if md.Summary != v.summary || md.WordCount != v.words || md.ReadingTime != v.reading || md.Image != v.image {
+				t.Errorf("expected %q %v %v %q, got %q %v %v %q",
+					v.summary, v.words, v.reading, v.image, md.Summary, md.WordCount, md.ReadingTime, md.Image)
+			}
+		})
+	}
+}
+
+func TestHighlighting(t *testing.T) {
+	src := "```go {hl_lines=[2]}\npackage main\n\nfunc main() {}\n```\n"
+
+	conf := &HighlightConfig{Classes: true, LineNumbers: true}
+	md, err := NewMarkdown(WithHighlighting(conf)).ParseBytes("code", []byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`class="chroma"`, `class="kn"`, `class="line hl"`, `class="ln"`} {
+		if !strings.Contains(md.Content, want) {
+			t.Errorf("expected %v in %v", want, md.Content)
+		}
+	}
+
+	md, err = NewMarkdown(WithHighlighting(nil)).ParseBytes("code", []byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(md.Content, `style="`) || strings.Contains(md.Content, `class="kn"`) {
+		t.Errorf("expected inline styles, got %v", md.Content)
+	}
+
+	out := fest.NewMemOutput()
+	g := fest.NewGenerator(context.Background(), "Highlight", &fest.GeneratorConfig{Output: out})
+	conf.AddStylesheet(g, "css/highlight.css")
+	if err := g.Generate(); err != nil {
+		t.Fatal(err)
+	}
+	css, err := fs.ReadFile(out, "css/highlight.css")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(css), ".chroma .kn") {
+		t.Errorf("unexpected stylesheet: %s", css)
+	}
+}
+
+func TestShortcodes(t *testing.T) {
+	m := NewMarkdown().(*MarkdownProcessor)
+	m.AddShortcode("callout", func(args ShortcodeArgs, inner templ.Component) templ.Component {
+		return templ.ComponentFunc(func(ctx context.Context, w io.Writer) error {
+			fmt.Fprintf(w, `<div class="callout %v">`, templ.EscapeString(args.Get("type")))
+			if err := inner.Render(ctx, w); err != nil {
+				return err
+			}
+			_, err := io.WriteString(w, "</div>")
+			return err
+		})
+	})
+	m.AddShortcode("youtube", func(args ShortcodeArgs, inner templ.Component) templ.Component {
+		return templ.Raw(fmt.Sprintf(`<iframe src="https://www.youtube.com/embed/%v" title="%v"></iframe>`,
+			args.Positional[0], args.Get("title")))
+	})
+
+	src := "# Video\n\n" +
+		"{{< callout type=\"warning\" >}}\nBe **careful**.\n\n{{< youtube abc123 title=\"A \\\"quoted\\\" title\" />}}\n{{< /callout >}}\n\n" +
+		"Inline {{< youtube xyz >}} video.\n\n" +
+		"```\n{{< youtube literal >}}\n```\n"
+
+	md, err := m.ParseBytes("video", []byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{
+		"<div class=\"callout warning\"><p>Be <strong>careful</strong>.</p>\n" +
+			`<iframe src="https://www.youtube.com/embed/abc123" title="A "quoted" title"></iframe>` + "\n</div>",
+		`<p>Inline <iframe src="https://www.youtube.com/embed/xyz" title=""></iframe> video.</p>`,
+		"<code>{{&lt; youtube literal &gt;}}\n</code>",
+	} {
+		if !strings.Contains(md.Content, want) {
+			t.Errorf("expected %q in %q", want, md.Content)
+		}
+	}
+	if strings.Contains(md.Content, "\uE000") {
+		t.Errorf("placeholder is left in %q", md.Content)
+	}
+	if md.Summary != "Video Inline video." {
+		t.Errorf("unexpected summary %q", md.Summary)
+	}
+
+	// shortcode syntax inside code is shown as is, e.g. in documentation
+	literal := "Use `{{< callout >}}` or ``{{< unknown `x` >}}``.\n\n" +
+		"    {{< unknown >}}\n\n" +
+		"- item\n\n    {{< youtube listed >}}\n"
+	md, err = m.ParseBytes("literal", []byte(literal))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"<code>{{&lt; callout &gt;}}</code>",
+		"<code>{{&lt; unknown `x` &gt;}}</code>",
+		"<pre><code>{{&lt; unknown &gt;}}\n</code></pre>",
+		`<iframe src="https://www.youtube.com/embed/listed" title=""></iframe>`,
+	} {
+		if !strings.Contains(md.Content, want) {
+			t.Errorf("expected %q in %q", want, md.Content)
+		}
+	}
+
+	// shortcode syntax inside the frontmatter is kept as is
+	fm := "---\ntitle: \"Using {{< callout >}}\"\n---\n{{< youtube abc123 >}}\n"
+	md, err = m.ParseBytes("frontmatter", []byte(fm))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var meta struct{ Title string }
+	if err := md.GetFrontmatter(&meta); err != nil {
+		t.Fatal(err)
+	}
+	if meta.Title != "Using {{< callout >}}" {
+		t.Errorf("unexpected title %q", meta.Title)
+	}
+	if !strings.Contains(md.Content, `src="https://www.youtube.com/embed/abc123"`) {
+		t.Errorf("shortcode is not rendered in %q", md.Content)
+	}
+
+	for _, src := range []string{"{{< unknown >}}", "{{< /callout >}}", "` {{< unknown >}}"} {
+		if _, err := m.ParseBytes("bad", []byte(src)); err == nil {
+			t.Errorf("expected error parsing %v", src)
+		}
+	}
+}
+
+func TestLinks(t *testing.T) {
+	fsys := fstest.MapFS{
+		"content/posts/first.md": {Data: []byte("[Install](../guides/install.md#linux) ![Diagram](./img/diagram.png) [Site](https://example.com) [Top](#top) [Abs](/about/)\n\n" +
+			"{{< note >}}[Guide](../guides/install.md) ![Again](img/diagram.png){{< /note >}}")},
+		"content/posts/img/diagram.png":  {Data: []byte("png")},
+		"content/guides/install.md":      {Data: []byte("[Back](../posts/first.md) ![Logo](../shared/logo.svg) [Notes](files/notes.pdf)")},
+		"content/guides/files/notes.pdf": {Data: []byte("pdf")},
+		"content/shared/logo.svg":        {Data: []byte("svg")},
+		"content/posts/shared/logo.svg":  {Data: []byte("other svg")},
+		"content/guides/draft.md":        {Data: []byte("---\ndraft: true\n---\n# Draft")},
+	}
+
+	m := NewMarkdown().(*MarkdownProcessor)
+	m.Recursive = true
+	m.PageURL = func(md *MarkdownData) string { return "/" + md.Path() + "/" }
+	m.AddShortcode("note", func(args ShortcodeArgs, inner templ.Component) templ.Component {
+		return templ.Join(templ.Raw(`<aside>`), inner, templ.Raw(`</aside>`))
+	})
+
+	mds, err := m.ParseFilesFS(fsys, "content")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(mds) != 2 {
+		t.Fatalf("expected 2 pages, got %v", len(mds))
+	}
+
+	install, first := mds[0], mds[1]
+	for _, v := range []struct {
+		md   *MarkdownData
+		want []string
+	}{
+		{first, []string{
+			`href="/guides/install/#linux"`, `src="/posts/first/img/diagram.png"`,
+			`href="https://example.com"`, `href="#top"`, `href="/about/"`,
+			`<aside><p><a href="/guides/install/">Guide</a> <img src="/posts/first/img/diagram.png" alt="Again"></p>`,
+		}},
+		{install, []string{`href="/posts/first/"`, `src="/guides/install/shared/logo.svg"`, `href="/guides/install/files/notes.pdf"`}},
+	} {
+		for _, want := range v.want {
+			if !strings.Contains(v.md.Content, want) {
+				t.Errorf("%v: expected %v in %v", v.md.RelPath, want, v.md.Content)
+			}
+		}
+	}
+
+	wantAssets := []Asset{{"content/shared/logo.svg", "shared/logo.svg"}, {"content/guides/files/notes.pdf", "files/notes.pdf"}}
+	if first.URL != "/posts/first/" || !slices.Equal(install.Assets, wantAssets) {
+		t.Errorf("unexpected URL or assets: %v %v", first.URL, install.Assets)
+	}
+	if len(first.Assets) != 1 {
+		t.Errorf("expected a single asset, got %v", first.Assets)
+	}
+	if first.Image != "/posts/first/img/diagram.png" {
+		t.Errorf("unexpected image %q", first.Image)
+	}
+
+	out := fest.NewMemOutput()
+	g := fest.NewGenerator(context.Background(), "Links", &fest.GeneratorConfig{Output: out, SourceFS: fsys})
+	first.CopyAssets(g)
+	if err := g.Generate(); err != nil {
+		t.Fatal(err)
+	}
+	if b, err := fs.ReadFile(out, "posts/first/img/diagram.png"); err != nil || string(b) != "png" {
+		t.Errorf("expected the copied asset, found %q (%v)", b, err)
+	}
+
+	for name, src := range map[string]string{
+		"missing":  "[Missing](./missing.md)",
+		"outside":  "![Outside](../../secret.png)",
+		"draft":    "[Draft](../guides/draft.md)",
+		"inner":    "{{< note >}}[Missing](./missing.md){{< /note >}}",
+		"same dst": "![Shared](../shared/logo.svg) ![Local](shared/logo.svg)",
+	} {
+		fsys := maps.Clone(fsys)
+		fsys["content/posts/first.md"] = &fstest.MapFile{Data: []byte(src)}
+		if _, err := m.ParseFilesFS(fsys, "content"); err == nil {
+			t.Errorf("%v: expected error", name)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package markdown
+
+import (
+	"os"
+	"strconv"
+	"time"
+)
+
+// standardKeys are the frontmatter keys that are always known in strict mode.
+var standardKeys = []string{"summary", "draft", "date", "publishDate", "expiryDate"}
+
+// decodeStandard sets the fields of md decoded from the recognized
+// frontmatter keys. The keys deciding whether md is published must convert
+// to their type, otherwise a broken draft could be published. The summary
+// and date are left unset if they don't, e.g. a free-form `date: last
+// tuesday`.
+func (md *MarkdownData) decodeStandard() error {
+	if md.fm == nil {
+		return nil
+	}
+
+	raw := map[string]any{}
+	if err := md.fm.Decode(&raw); err != nil {
+		return &FrontmatterError{Path: md.name, Err: err}
+	}
+
+	var (
+		summary struct {
+			Summary string `yaml:"summary" toml:"summary"`
+		}
+		date struct {
+			Date time.Time `yaml:"date" toml:"date"`
+		}
+		draft struct {
+			Draft bool `yaml:"draft" toml:"draft"`
+		}
+		publishDate struct {
+			PublishDate time.Time `yaml:"publishDate" toml:"publishDate"`
+		}
+		expiryDate struct {
+			ExpiryDate time.Time `yaml:"expiryDate" toml:"expiryDate"`
+		}
+	)
+	_ = md.fm.Decode(&summary)
+	_ = md.fm.Decode(&date)
+	for _, v := range []struct {
+		key string
+		dst any
+	}{{"draft", &draft}, {"publishDate", &publishDate}, {"expiryDate", &expiryDate}} {
+		if _, ok := raw[v.key]; !ok {
+			continue
+		}
+		if err := md.fm.Decode(v.dst); err != nil {
+			return &FrontmatterError{Path: md.name, Key: v.key, Err: err}
+		}
+	}
+	md.Summary, md.Draft, md.Date = summary.Summary, draft.Draft, date.Date
+	md.PublishDate, md.ExpiryDate = publishDate.PublishDate, expiryDate.ExpiryDate
+	return nil
+}
+
+// IsPublished reports whether md is visible at now, i.e. it's not a draft,
+// its PublishDate, or Date if unset, is not after now, and its ExpiryDate
+// is after now.
+func (md *MarkdownData) IsPublished(now time.Time) bool {
+	publish := md.PublishDate
+	if publish.IsZero() {
+		publish = md.Date
+	}
+	switch {
+	case md.Draft:
+		return false
+	case !publish.IsZero() && publish.After(now):
+		return false
+	case !md.ExpiryDate.IsZero() && !md.ExpiryDate.After(now):
+		return false
+	}
+	return true
+}
+
+// preview reports whether unpublished files should be included.
+func (m *MarkdownProcessor) preview() bool {
+	if v, ok := os.LookupEnv("FEST_PREVIEW"); ok {
+		preview, _ := strconv.ParseBool(v)
+		return preview
+	}
+	return m.Preview
+}
+
+// now returns the time used to check whether the files are published.
+func (m *MarkdownProcessor) now() time.Time {
+	if m.Now.IsZero() {
+		return time.Now()
+	}
+	return m.Now
+}
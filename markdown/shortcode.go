@@ -0,0 +1,338 @@
+package markdown
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/a-h/templ"
+	"github.com/yuin/goldmark/text"
+)
+
+// Shortcode renders a shortcode written in markdown as
+// `{{< name args >}}inner{{< /name >}}` or `{{< name args >}}` without inner
+// content. The inner content is rendered from markdown, and it's a no-op
+// component when there is none.
+type Shortcode func(args ShortcodeArgs, inner templ.Component) templ.Component
+
+// ShortcodeArgs are the arguments of a shortcode, e.g. `{{< figure
+// "/img/a.png" caption="A figure" >}}` has "/img/a.png" as a positional
+// argument and caption as a named one. Values may be quoted with `"`.
+type ShortcodeArgs struct {
+	Named      map[string]string
+	Positional []string
+}
+
+// Get returns the named argument, or an empty string if it's not set.
+func (a ShortcodeArgs) Get(name string) string { return a.Named[name] }
+
+// AddShortcode registers fn as the shortcode name. It replaces the
+// shortcode with the same name.
+func (m *MarkdownProcessor) AddShortcode(name string, fn Shortcode) *MarkdownProcessor {
+	if m.shortcodes == nil {
+		m.shortcodes = map[string]Shortcode{}
+	}
+	m.shortcodes[name] = fn
+	return m
+}
+
+var (
+	shortcodeTagRe   = regexp.MustCompile(`\{\{<\s*(/)?\s*([\w-]+)(.*?)>\}\}`)
+	shortcodeTokenRe = regexp.MustCompile(`(<p>)?\x{E000}(\d+)\x{E001}(</p>)?`)
+	shortcodeArgRe   = regexp.MustCompile(`(?:([\w-]+)=)?(?:"((?:[^"\\]|\\.)*)"|(\S+))`)
+	listItemRe       = regexp.MustCompile(`^([-*+]|\d+[.)])(\s|$)`)
+)
+
+// shortcodeToken returns the placeholder of the i-th shortcode. It's made of
+// private use characters so it's kept as is by goldmark.
+func shortcodeToken(i int) string { return fmt.Sprintf("\uE000%d\uE001", i) }
+
+type shortcodeTag struct {
+	start, end     int
+	name, args     string
+	closing, alone bool
+}
+
+// extractShortcodes replaces the shortcodes in src with placeholders and
+// returns the rendered shortcodes in the order of the placeholders.
+// Shortcodes inside the frontmatter, code blocks and code spans are kept as is.
+// The relative links in their inner content are rewritten with lk if it's not nil.
+func (m *MarkdownProcessor) extractShortcodes(src []byte, lk *pageLinks) ([]byte, []string, error) {
+	if len(m.shortcodes) < 1 {
+		return src, nil, nil
+	}
+
+	code := literalRanges(src)
+	var tags []shortcodeTag
+	for _, loc := range shortcodeTagRe.FindAllSubmatchIndex(src, -1) {
+		if inRanges(code, loc[0]) {
+			continue
+		}
+		args := strings.TrimSpace(string(src[loc[6]:loc[7]]))
+		tags = append(tags, shortcodeTag{
+			start:   loc[0],
+			end:     loc[1],
+			name:    string(src[loc[4]:loc[5]]),
+			args:    strings.TrimSpace(strings.TrimSuffix(args, "/")),
+			closing: loc[2] >= 0,
+			alone:   strings.HasSuffix(args, "/"),
+		})
+	}
+	if len(tags) < 1 {
+		return src, nil, nil
+	}
+
+	var out bytes.Buffer
+	var rendered []string
+	last := 0
+	for i := 0; i < len(tags); i++ {
+		t := tags[i]
+		if t.closing {
+			return nil, nil, fmt.Errorf("unexpected closing shortcode \"%v\"", t.name)
+		}
+		fn, ok := m.shortcodes[t.name]
+		if !ok {
+			return nil, nil, fmt.Errorf("unknown shortcode \"%v\"", t.name)
+		}
+
+		var inner templ.Component = templ.NopComponent
+		end := t.end
+		if j := closingTag(tags, i); !t.alone && j > 0 {
+			html, err := m.renderInner(src[t.end:tags[j].start], lk)
+			if err != nil {
+				return nil, nil, fmt.Errorf("error rendering shortcode \"%v\": %w", t.name, err)
+			}
+			inner, end = templ.Raw(html), tags[j].end
+			i = j
+		}
+
+		var buf bytes.Buffer
+		if err := fn(parseShortcodeArgs(t.args), inner).Render(context.Background(), &buf); err != nil {
+			return nil, nil, fmt.Errorf("error rendering shortcode \"%v\": %w", t.name, err)
+		}
+
+		out.Write(src[last:t.start])
+		out.WriteString(shortcodeToken(len(rendered)))
+		rendered = append(rendered, buf.String())
+		last = end
+	}
+	out.Write(src[last:])
+	return out.Bytes(), rendered, nil
+}
+
+// renderInner renders the inner content of a shortcode as markdown.
+func (m *MarkdownProcessor) renderInner(src []byte, lk *pageLinks) (string, error) {
+	src, rendered, err := m.extractShortcodes(src, lk)
+	if err != nil {
+		return "", err
+	}
+
+	doc := m.md.Parser().Parse(text.NewReader(src))
+	if lk != nil {
+		if err := lk.rewrite(doc); err != nil {
+			return "", err
+		}
+	}
+	var buf bytes.Buffer
+	if err := m.md.Renderer().Render(&buf, src, doc); err != nil {
+		return "", err
+	}
+	return replaceShortcodes(buf.String(), rendered), nil
+}
+
+// replaceShortcodes replaces the placeholders in html with the rendered
+// shortcodes, unwrapping the ones that are a paragraph on their own.
+func replaceShortcodes(html string, rendered []string) string {
+	if len(rendered) < 1 {
+		return html
+	}
+	return shortcodeTokenRe.ReplaceAllStringFunc(html, func(s string) string {
+		sub := shortcodeTokenRe.FindStringSubmatch(s)
+		i, _ := strconv.Atoi(sub[2])
+		if i >= len(rendered) {
+			return s
+		}
+		if len(sub[1]) > 0 && len(sub[3]) > 0 {
+			return rendered[i]
+		}
+		return sub[1] + rendered[i] + sub[3]
+	})
+}
+
+// closingTag returns the index of the tag closing tags[i], or -1 if there is none.
+func closingTag(tags []shortcodeTag, i int) int {
+	depth := 0
+	for j := i + 1; j < len(tags); j++ {
+		if tags[j].name != tags[i].name || tags[j].alone {
+			continue
+		}
+		if !tags[j].closing {
+			depth++
+			continue
+		}
+		if depth == 0 {
+			return j
+		}
+		depth--
+	}
+	return -1
+}
+
+func parseShortcodeArgs(s string) ShortcodeArgs {
+	args := ShortcodeArgs{Named: map[string]string{}}
+	for _, m := range shortcodeArgRe.FindAllStringSubmatch(s, -1) {
+		v := m[3]
+		if len(m[3]) < 1 {
+			v = strings.ReplaceAll(m[2], `\"`, `"`)
+		}
+		if len(m[1]) > 0 {
+			args.Named[m[1]] = v
+		} else {
+			args.Positional = append(args.Positional, v)
+		}
+	}
+	return args
+}
+
+// literalRanges returns the byte ranges of the frontmatter, code blocks
+// and code spans in src.
+func literalRanges(src []byte) [][2]int {
+	start := frontmatterEnd(src)
+	blocks := codeBlockRanges(src, start)
+	ranges := slices.Clone(blocks)
+	if start > 0 {
+		ranges = append(ranges, [2]int{0, start})
+	}
+	for i := start; i < len(src); i++ {
+		switch {
+		case src[i] == '\\':
+			i++
+		case src[i] == '`' && !inRanges(blocks, i):
+			n := len(src[i:]) - len(bytes.TrimLeft(src[i:], "`"))
+			if end := closingBackticks(src, i+n, n); end > 0 {
+				ranges = append(ranges, [2]int{i, end})
+				i = end - 1
+			} else {
+				i += n - 1
+			}
+		}
+	}
+	return ranges
+}
+
+// frontmatterEnd returns the end of the frontmatter at the start of src,
+// or 0 if there is none. Like the frontmatter extension, it's delimited by
+// lines of the same three or more '-' or '+', and an unclosed one takes
+// the whole src.
+func frontmatterEnd(src []byte) int {
+	line, _, _ := bytes.Cut(src, []byte("\n"))
+	delim := bytes.TrimSuffix(line, []byte("\r"))
+	if len(delim) < 3 || (delim[0] != '-' && delim[0] != '+') ||
+		len(bytes.Trim(delim, string(delim[0]))) > 0 {
+		return 0
+	}
+
+	for off := len(line) + 1; off < len(src); {
+		end := bytes.IndexByte(src[off:], '\n')
+		if end < 0 {
+			end = len(src)
+		} else {
+			end += off + 1
+		}
+		if bytes.Equal(bytes.TrimRight(src[off:end], "\r\n"), delim) {
+			return end
+		}
+		off = end
+	}
+	return len(src)
+}
+
+// closingBackticks returns the end of the run of n backticks closing a code
+// span, searching src from off until the end of the paragraph, or -1 if
+// there is none.
+func closingBackticks(src []byte, off, n int) int {
+	for i := off; i < len(src); {
+		switch src[i] {
+		case '`':
+			run := len(src[i:]) - len(bytes.TrimLeft(src[i:], "`"))
+			if run == n {
+				return i + run
+			}
+			i += run
+			continue
+		case '\n':
+			line, _, _ := bytes.Cut(src[i+1:], []byte("\n"))
+			if len(bytes.TrimSpace(line)) < 1 {
+				return -1
+			}
+		}
+		i++
+	}
+	return -1
+}
+
+// codeBlockRanges returns the byte ranges of the fenced and indented code
+// blocks in src from the offset from. Indented lines inside a list are not
+// counted as code.
+func codeBlockRanges(src []byte, from int) [][2]int {
+	var ranges [][2]int
+	var fence string
+	start, indented := 0, -1
+	prevBlank, inList := true, false
+	for off := from; off < len(src); {
+		end := bytes.IndexByte(src[off:], '\n')
+		if end < 0 {
+			end = len(src)
+		} else {
+			end += off + 1
+		}
+
+		raw := string(src[off:end])
+		line := strings.TrimLeft(raw, " ")
+		blank := len(strings.TrimSpace(raw)) < 1
+		isIndented := strings.HasPrefix(raw, "    ") || strings.HasPrefix(raw, "\t")
+
+		if indented >= 0 && !blank && !isIndented {
+			ranges = append(ranges, [2]int{indented, off})
+			indented = -1
+		}
+		switch {
+		case len(fence) > 0:
+			if strings.HasPrefix(line, fence) {
+				ranges = append(ranges, [2]int{start, end})
+				fence = ""
+			}
+		case indented >= 0 || blank:
+		case isIndented && prevBlank && !inList:
+			indented = off
+		case strings.HasPrefix(line, "```") || strings.HasPrefix(line, "~~~"):
+			fence = line[:3]
+			start = off
+		case isIndented:
+		default:
+			inList = listItemRe.MatchString(line) || (inList && !prevBlank)
+		}
+		prevBlank = blank
+		off = end
+	}
+	if len(fence) > 0 {
+		ranges = append(ranges, [2]int{start, len(src)})
+	}
+	if indented >= 0 {
+		ranges = append(ranges, [2]int{indented, len(src)})
+	}
+	return ranges
+}
+
+func inRanges(ranges [][2]int, i int) bool {
+	for _, r := range ranges {
+		if i >= r[0] && i < r[1] {
+			return true
+		}
+	}
+	return false
+}
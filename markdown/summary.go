@@ -0,0 +1,88 @@
+package markdown
+
+import (
+	"bytes"
+	"strings"
+	"time"
+
+	"github.com/yuin/goldmark/ast"
+)
+
+// moreSeparator separates the summary from the rest of the content.
+const moreSeparator = "<!--more-->"
+
+// inspect sets the summary, word count, reading time and image of md from doc.
+func (md *MarkdownData) inspect(doc ast.Node, src []byte, summaryWords, wpm int) {
+	var text, summary strings.Builder
+	more := false
+
+	for block := doc.FirstChild(); block != nil; block = block.NextSibling() {
+		if html, ok := block.(*ast.HTMLBlock); ok && !more && isMore(html, src) {
+			more = true
+			summary.WriteString(text.String())
+			continue
+		}
+		writeText(&text, block, src)
+		text.WriteByte(' ')
+	}
+
+	ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if img, ok := n.(*ast.Image); ok && entering {
+			md.Image = string(img.Destination)
+			return ast.WalkStop, nil
+		}
+		return ast.WalkContinue, nil
+	})
+
+	// shortcodes are not part of the text
+	words := strings.Fields(shortcodeTokenRe.ReplaceAllString(text.String(), " "))
+	md.WordCount = len(words)
+	if md.WordCount > 0 {
+		minutes := (md.WordCount + wpm - 1) / wpm
+		md.ReadingTime = time.Duration(minutes) * time.Minute
+	}
+
+	switch {
+	case len(md.Summary) > 0:
+	case more:
+		md.Summary = strings.Join(strings.Fields(shortcodeTokenRe.ReplaceAllString(summary.String(), " ")), " ")
+	default:
+		md.Summary = strings.Join(words[:min(summaryWords, len(words))], " ")
+	}
+}
+
+// isMore reports whether block is the `<!--more-->` separator.
+func isMore(block *ast.HTMLBlock, src []byte) bool {
+	var b bytes.Buffer
+	lines := block.Lines()
+	for i := range lines.Len() {
+		seg := lines.At(i)
+		b.Write(seg.Value(src))
+	}
+	return strings.TrimSpace(b.String()) == moreSeparator
+}
+
+// writeText writes the plain text of n to b, skipping code blocks, HTML and images.
+func writeText(b *strings.Builder, n ast.Node, src []byte) {
+	ast.Walk(n, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			if n.Type() == ast.TypeBlock {
+				b.WriteByte(' ')
+			}
+			return ast.WalkContinue, nil
+		}
+
+		switch n := n.(type) {
+		case *ast.FencedCodeBlock, *ast.CodeBlock, *ast.HTMLBlock, *ast.RawHTML, *ast.Image:
+			return ast.WalkSkipChildren, nil
+		case *ast.Text:
+			b.Write(n.Value(src))
+			if n.SoftLineBreak() || n.HardLineBreak() {
+				b.WriteByte(' ')
+			}
+		case *ast.String:
+			b.Write(n.Value)
+		}
+		return ast.WalkContinue, nil
+	})
+}
@@ -0,0 +1,51 @@
+package markdown
+
+import (
+	"github.com/yuin/goldmark/ast"
+	"go.abhg.dev/goldmark/toc"
+)
+
+// TOCItem is a header in the table of content. Items that only group
+// deeper headers, e.g. a `###` without a preceding `##`, have empty Title and ID.
+type TOCItem struct {
+	Title string
+
+	// ID is the anchor ID of the header without "#".
+	ID string
+
+	// Depth is the level of the header, e.g. 2 for `##`, or 0 for grouping items.
+	Depth int
+
+	Children []*TOCItem
+}
+
+// headingLevels returns the level of the headings in doc by their ID.
+func headingLevels(doc ast.Node) map[string]int {
+	levels := map[string]int{}
+	ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		h, ok := n.(*ast.Heading)
+		if !entering || !ok {
+			return ast.WalkContinue, nil
+		}
+		if id, ok := h.AttributeString("id"); ok {
+			if b, ok := id.([]byte); ok {
+				levels[string(b)] = h.Level
+			}
+		}
+		return ast.WalkSkipChildren, nil
+	})
+	return levels
+}
+
+func tocItems(items toc.Items, levels map[string]int) []*TOCItem {
+	var res []*TOCItem
+	for _, v := range items {
+		res = append(res, &TOCItem{
+			Title:    string(v.Title),
+			ID:       string(v.ID),
+			Depth:    levels[string(v.ID)],
+			Children: tocItems(v.Items, levels),
+		})
+	}
+	return res
+}
@@ -0,0 +1,167 @@
+package fest
+
+import (
+	"cmp"
+	"context"
+	"path"
+	"slices"
+	"strings"
+)
+
+// NavItem is a node of the navigation tree built from the paths of all
+// routes of the Generator, see GetNav.
+type NavItem struct {
+	// Title is the route's title. Items without a route at their path,
+	// such as "/docs/" when only "/docs/intro" is added, use the path
+	// segment instead. The root uses the site's name if it has no title.
+	Title string
+
+	// Path is the URL path the item is served at, e.g. "/docs/intro/".
+	Path string
+
+	// Weight orders the item among its siblings, the lighter one first.
+	// Items with the same weight keep the order their routes are added.
+	Weight int
+
+	// Menus are the names of the menus the item belongs to, see GetMenu.
+	Menus []string
+
+	// HasRoute reports whether there is a route at Path.
+	HasRoute bool
+
+	Parent   *NavItem
+	Children []*NavItem
+
+	segment string
+}
+
+// IsActive reports whether n is the item of the route being rendered.
+func (n *NavItem) IsActive(ctx context.Context) bool { return n == GetNavItem(ctx) }
+
+// HasActive reports whether n or any of its descendants is active.
+func (n *NavItem) HasActive(ctx context.Context) bool {
+	for cur := GetNavItem(ctx); cur != nil; cur = cur.Parent {
+		if cur == n {
+			return true
+		}
+	}
+	return false
+}
+
+// Section returns the top-level item n is in, i.e. the ancestor that
+// is a child of the root. It returns n itself for the top-level items and the root.
+func (n *NavItem) Section() *NavItem {
+	for n.Parent != nil && n.Parent.Parent != nil {
+		n = n.Parent
+	}
+	return n
+}
+
+// Siblings returns the other children of n's parent.
+func (n *NavItem) Siblings() []*NavItem {
+	if n.Parent == nil {
+		return nil
+	}
+	return slices.DeleteFunc(slices.Clone(n.Parent.Children), func(v *NavItem) bool { return v == n })
+}
+
+// InMenu reports whether n belongs to the menu name.
+func (n *NavItem) InMenu(name string) bool { return slices.Contains(n.Menus, name) }
+
+// GetNav gets the root of the navigation tree, or nil outside of render context.
+func GetNav(ctx context.Context) *NavItem {
+	n := GetNavItem(ctx)
+	for n != nil && n.Parent != nil {
+		n = n.Parent
+	}
+	return n
+}
+
+// GetNavItem gets the navigation item of the current route, or nil
+// outside of render context.
+func GetNavItem(ctx context.Context) *NavItem { return getInfo(ctx).nav }
+
+// GetMenu gets the items that belong to the menu name, ordered the
+// same way as in the navigation tree.
+func GetMenu(ctx context.Context, name string) []*NavItem {
+	var items []*NavItem
+	var walk func(n *NavItem)
+	walk = func(n *NavItem) {
+		if n.InMenu(name) {
+			items = append(items, n)
+		}
+		for _, c := range n.Children {
+			walk(c)
+		}
+	}
+	if root := GetNav(ctx); root != nil {
+		walk(root)
+	}
+	return items
+}
+
+// navTree returns the navigation tree of the current routes along with
+// the item of each route. The tree is cached until resetNav is called.
+func (g *Generator) navTree() map[*Route]*NavItem {
+	g.navMu.Lock()
+	defer g.navMu.Unlock()
+
+	if g.navItems != nil {
+		return g.navItems
+	}
+
+	root := &NavItem{Title: g.siteName, Path: "/"}
+	items := make(map[*Route]*NavItem, len(g.routes))
+	for _, r := range g.routes {
+		n := root
+		p := strings.Trim(path.Clean("/"+r.path), "/")
+		if len(p) > 0 {
+			for _, seg := range strings.Split(p, "/") {
+				idx := slices.IndexFunc(n.Children, func(v *NavItem) bool { return v.segment == seg })
+				if idx < 0 {
+					child := &NavItem{
+						Title:   seg,
+						Path:    path.Join(n.Path, seg) + "/",
+						Parent:  n,
+						segment: seg,
+					}
+					n.Children = append(n.Children, child)
+					idx = len(n.Children) - 1
+				}
+				n = n.Children[idx]
+			}
+		}
+
+		n.Path = r.canonicalPath()
+		n.HasRoute = true
+		n.Weight = r.weight
+		n.Menus = r.menus
+		if r.title != nil && len(*r.title) > 0 {
+			n.Title = *r.title
+		}
+		items[r] = n
+	}
+
+	var sort func(n *NavItem)
+	sort = func(n *NavItem) {
+		slices.SortStableFunc(n.Children, func(a, b *NavItem) int { return cmp.Compare(a.Weight, b.Weight) })
+		for _, c := range n.Children {
+			sort(c)
+		}
+	}
+	sort(root)
+
+	g.navItems = items
+	return items
+}
+
+// resetNav discards the cached navigation tree. It's called whenever
+// a route is added or changes what the tree is built from.
+func (g *Generator) resetNav() {
+	if g == nil {
+		return
+	}
+	g.navMu.Lock()
+	g.navItems = nil
+	g.navMu.Unlock()
+}
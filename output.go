@@ -0,0 +1,338 @@
+package fest
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing/fstest"
+	"time"
+)
+
+// Output is the target the Generator writes the generated files to.
+// All names are slash-separated paths relative to the output root.
+type Output interface {
+	// Mkdir creates the directory name along with any necessary parents.
+	Mkdir(name string, mode fs.FileMode) error
+
+	// Create creates or truncates the file name, creating its parent
+	// directories as needed. The file is only guaranteed to be written
+	// after the returned writer is closed. Zero modTime means the current time.
+	Create(name string, mode fs.FileMode, modTime time.Time) (io.WriteCloser, error)
+
+	// Close finalizes the output. It's called by Generate when it's done.
+	Close() error
+}
+
+// SymlinkOutput is an Output that can create symlinks.
+// It's required to copy with SymlinkPreserve.
+type SymlinkOutput interface {
+	Output
+
+	// Symlink creates name as a symlink to target, creating its parent
+	// directories as needed.
+	Symlink(target, name string) error
+}
+
+// slashPath converts p into a slash-separated path accepted by fs.FS and Output.
+func slashPath(p string) string {
+	return path.Clean(strings.TrimPrefix(filepath.ToSlash(p), "/"))
+}
+
+func validOutPath(op, name string) error {
+	if !fs.ValidPath(name) {
+		return &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	return nil
+}
+
+// NewDirOutput creates an Output that writes to the dir directory
+// in the OS filesystem. This is the default Output of the Generator.
+func NewDirOutput(dir string) Output { return &dirOutput{dir: dir} }
+
+type dirOutput struct {
+	dir  string
+	root *os.Root
+}
+
+func (d *dirOutput) open() error {
+	if d.root != nil {
+		return nil
+	}
+	if err := os.MkdirAll(d.dir, 0o744); err != nil {
+		return fmt.Errorf("error making dir: %w", err)
+	}
+
+	root, err := os.OpenRoot(d.dir)
+	if err != nil {
+		return fmt.Errorf("error opening root: %w", err)
+	}
+	d.root = root
+	return nil
+}
+
+func (d *dirOutput) Mkdir(name string, mode fs.FileMode) error {
+	if err := validOutPath("mkdir", name); err != nil {
+		return err
+	}
+	if err := d.open(); err != nil {
+		return err
+	}
+	return d.root.MkdirAll(filepath.FromSlash(name), mode.Perm())
+}
+
+func (d *dirOutput) Create(name string, mode fs.FileMode, modTime time.Time) (io.WriteCloser, error) {
+	if err := validOutPath("create", name); err != nil {
+		return nil, err
+	}
+	if err := d.Mkdir(path.Dir(name), 0o744); err != nil {
+		return nil, fmt.Errorf("error while making parent directory: %w", err)
+	}
+
+	f, err := d.root.OpenFile(filepath.FromSlash(name), os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode.Perm())
+	if err != nil {
+		return nil, err
+	}
+	// the mode is only used when the file doesn't exist yet
+	if err := f.Chmod(mode.Perm()); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &dirFile{File: f, root: d.root, name: filepath.FromSlash(name), modTime: modTime}, nil
+}
+
+func (d *dirOutput) Symlink(target, name string) error {
+	if err := validOutPath("symlink", name); err != nil {
+		return err
+	}
+	if err := d.Mkdir(path.Dir(name), 0o744); err != nil {
+		return fmt.Errorf("error while making parent directory: %w", err)
+	}
+
+	p := filepath.FromSlash(name)
+	if err := d.root.Remove(p); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return d.root.Symlink(target, p)
+}
+
+func (d *dirOutput) Close() error {
+	if d.root == nil {
+		return nil
+	}
+	err := d.root.Close()
+	d.root = nil
+	return err
+}
+
+type dirFile struct {
+	*os.File
+	root    *os.Root
+	name    string
+	modTime time.Time
+}
+
+func (f *dirFile) Close() error {
+	if err := f.File.Close(); err != nil {
+		return err
+	}
+	if f.modTime.IsZero() {
+		return nil
+	}
+	return f.root.Chtimes(f.name, f.modTime, f.modTime)
+}
+
+// MemOutput is an in-memory Output. It implements fs.FS so the generated
+// files can be inspected without touching the disk.
+type MemOutput struct {
+	mu    sync.Mutex
+	files fstest.MapFS
+}
+
+// NewMemOutput creates an empty MemOutput.
+func NewMemOutput() *MemOutput { return &MemOutput{files: fstest.MapFS{}} }
+
+// Open opens the generated file name.
+func (m *MemOutput) Open(name string) (fs.File, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.files.Open(name)
+}
+
+func (m *MemOutput) Mkdir(name string, mode fs.FileMode) error {
+	if err := validOutPath("mkdir", name); err != nil {
+		return err
+	}
+	if name == "." {
+		return nil
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.files[name] = &fstest.MapFile{Mode: fs.ModeDir | mode.Perm(), ModTime: time.Now()}
+	return nil
+}
+
+func (m *MemOutput) Create(name string, mode fs.FileMode, modTime time.Time) (io.WriteCloser, error) {
+	if err := validOutPath("create", name); err != nil {
+		return nil, err
+	}
+	if modTime.IsZero() {
+		modTime = time.Now()
+	}
+	return &bufFile{close: func(b []byte) error {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		m.files[name] = &fstest.MapFile{Data: b, Mode: mode.Perm(), ModTime: modTime}
+		return nil
+	}}, nil
+}
+
+func (m *MemOutput) Symlink(target, name string) error {
+	if err := validOutPath("symlink", name); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.files[name] = &fstest.MapFile{Data: []byte(target), Mode: fs.ModeSymlink | 0o777, ModTime: time.Now()}
+	return nil
+}
+
+func (m *MemOutput) Close() error { return nil }
+
+// bufFile buffers the written content and passes it to close when closed.
+type bufFile struct {
+	bytes.Buffer
+	close func([]byte) error
+}
+
+func (f *bufFile) Close() error { return f.close(f.Bytes()) }
+
+// NewZipOutput creates an Output that writes a zip archive to w.
+// Closing the Output doesn't close w.
+func NewZipOutput(w io.Writer) Output {
+	zw := zip.NewWriter(w)
+	return &archiveOutput{
+		now:   time.Now(),
+		close: zw.Close,
+		write: func(name string, mode fs.FileMode, modTime time.Time, data []byte) error {
+			h := &zip.FileHeader{Name: name, Method: zip.Deflate, Modified: modTime}
+			if mode.IsDir() {
+				h.Name += "/"
+				h.Method = zip.Store
+			} else if mode&fs.ModeSymlink != 0 {
+				h.Method = zip.Store
+			}
+			h.SetMode(mode)
+
+			f, err := zw.CreateHeader(h)
+			if err != nil {
+				return err
+			}
+			_, err = f.Write(data)
+			return err
+		},
+	}
+}
+
+// NewTarGzOutput creates an Output that writes a gzip-compressed tar archive to w.
+// Closing the Output doesn't close w.
+func NewTarGzOutput(w io.Writer) Output {
+	gw := gzip.NewWriter(w)
+	tw := tar.NewWriter(gw)
+	return &archiveOutput{
+		now:   time.Now(),
+		close: func() error { return errors.Join(tw.Close(), gw.Close()) },
+		write: func(name string, mode fs.FileMode, modTime time.Time, data []byte) error {
+			h := &tar.Header{
+				Name:     name,
+				Mode:     int64(mode.Perm()),
+				ModTime:  modTime,
+				Size:     int64(len(data)),
+				Typeflag: tar.TypeReg,
+			}
+			if mode.IsDir() {
+				h.Name += "/"
+				h.Typeflag = tar.TypeDir
+			} else if mode&fs.ModeSymlink != 0 {
+				h.Typeflag = tar.TypeSymlink
+				h.Linkname = string(data)
+				h.Size = 0
+				data = nil
+			}
+
+			if err := tw.WriteHeader(h); err != nil {
+				return err
+			}
+			_, err := tw.Write(data)
+			return err
+		},
+	}
+}
+
+// archiveOutput writes each file as a single archive entry when the file is closed.
+// Symlinks are written with the target as their data.
+type archiveOutput struct {
+	mu    sync.Mutex
+	now   time.Time
+	dirs  map[string]bool
+	write func(name string, mode fs.FileMode, modTime time.Time, data []byte) error
+	close func() error
+}
+
+func (a *archiveOutput) Mkdir(name string, mode fs.FileMode) error {
+	if err := validOutPath("mkdir", name); err != nil {
+		return err
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.dirs == nil {
+		a.dirs = map[string]bool{}
+	}
+	var missing []string
+	for p := name; p != "." && !a.dirs[p]; p = path.Dir(p) {
+		missing = append(missing, p)
+	}
+	for _, p := range slices.Backward(missing) {
+		a.dirs[p] = true
+		if err := a.write(p, fs.ModeDir|mode.Perm(), a.now, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (a *archiveOutput) Create(name string, mode fs.FileMode, modTime time.Time) (io.WriteCloser, error) {
+	if err := validOutPath("create", name); err != nil {
+		return nil, err
+	}
+	if modTime.IsZero() {
+		modTime = a.now
+	}
+	return &bufFile{close: func(b []byte) error {
+		a.mu.Lock()
+		defer a.mu.Unlock()
+		return a.write(name, mode.Perm(), modTime, b)
+	}}, nil
+}
+
+func (a *archiveOutput) Symlink(target, name string) error {
+	if err := validOutPath("symlink", name); err != nil {
+		return err
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.write(name, fs.ModeSymlink|0o777, a.now, []byte(target))
+}
+
+func (a *archiveOutput) Close() error { return a.close() }
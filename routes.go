@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"math"
+	"net/http"
 	"path/filepath"
 	"slices"
 	"strconv"
@@ -27,6 +28,15 @@ type Routes[T any] struct {
 	useData bool
 
 	baseConfig *temfest.BaseConfig
+	meta       *temfest.Meta
+	header     http.Header
+
+	layout string
+	slots  map[string]templ.Component
+
+	userData map[string]any
+	weight   int
+	menus    []string
 }
 
 // NewRoutes creates routes from the data with specified size.
@@ -53,6 +63,8 @@ type Pagination[T any] struct {
 	Chunk []T
 }
 
+func (p *Pagination[T]) page() int { return p.Current }
+
 // NewPaginatedRoutes creates routes that will be paginated from data with specified size.
 func NewPaginatedRoutes[T any](path string, data []T, size int) *Routes[*Pagination[T]] {
 	it := slices.Chunk(data, size)
@@ -99,18 +111,55 @@ func (rs *Routes[T]) AddToGenerator(g *Generator, fn RouteFunc[T]) {
 		path := strings.ReplaceAll(rs.path, "{s}", slug)
 		title := strings.ReplaceAll(t, "{s}", slug)
 
-		if rs.baseConfig == nil {
-			rs.baseConfig = &temfest.BaseConfig{}
+		var conf temfest.BaseConfig
+		if rs.baseConfig != nil {
+			conf = *rs.baseConfig
 		}
 		if rp.baseConfig != nil {
-			inheritChildValues(rs.baseConfig, rp.baseConfig)
-			rs.HeadBody.Head(rp.HeadBody.head...)
-			rs.HeadBody.Body(rp.HeadBody.body...)
+			inheritChildValues(&conf, rp.baseConfig)
 		}
-		r := g.AddRoute(path, comp).SetTitle(title).BaseConfig(*rs.baseConfig)
+		r := g.AddRoute(path, comp).SetTitle(title).BaseConfig(conf)
+
+		var meta temfest.Meta
+		if rs.meta != nil {
+			meta = *rs.meta
+		}
+		if rp.meta != nil {
+			inheritChildValues(&meta, rp.meta)
+		}
+		r.SetMeta(meta)
 
 		r.HeadBody.Head(rs.HeadBody.head...)
 		r.HeadBody.Body(rs.HeadBody.body...)
+		r.HeadBody.Head(rp.HeadBody.head...)
+		r.HeadBody.Body(rp.HeadBody.body...)
+
+		for _, h := range []http.Header{rs.header, rp.header} {
+			for k, v := range h {
+				if r.header == nil {
+					r.header = http.Header{}
+				}
+				r.header[k] = slices.Clone(v)
+			}
+		}
+
+		for _, data := range []map[string]any{rs.userData, rp.userData} {
+			for k, v := range data {
+				r.SetData(k, v)
+			}
+		}
+		r.SetWeight(ternary(rp.weight != 0, rp.weight, rs.weight))
+		r.Menu(rs.menus...).Menu(rp.menus...)
+		if p, ok := any(d).(interface{ page() int }); ok {
+			r.page = p.page()
+		}
+
+		r.Layout(ternary(len(rp.layout) > 0, rp.layout, rs.layout))
+		for _, slots := range []map[string]templ.Component{rs.slots, rp.slots} {
+			for name, comp := range slots {
+				r.Slot(name, comp)
+			}
+		}
 	}
 }
 
@@ -122,12 +171,65 @@ func (rs *Routes[T]) SetTitle(title string) *Routes[T] {
 }
 
 // BaseConfig sets the temfest.Base config for the routes.
-// Unset/empty field will be no-op.
+// Unset/empty field will be inherited from the Generator's BaseConfig.
 func (rs *Routes[T]) BaseConfig(conf temfest.BaseConfig) *Routes[T] {
 	rs.baseConfig = &conf
 	return rs
 }
 
+// SetMeta sets the page metadata for the routes.
+// Unset/empty field will be inherited from the Generator's BaseConfig.
+func (rs *Routes[T]) SetMeta(meta temfest.Meta) *Routes[T] {
+	rs.meta = &meta
+	return rs
+}
+
+// SetHeader sets a response header for each item route.
+func (rs *Routes[T]) SetHeader(key, value string) *Routes[T] {
+	if rs.header == nil {
+		rs.header = http.Header{}
+	}
+	rs.header.Set(key, value)
+	return rs
+}
+
+// Layout sets the name of the layout each item route is rendered with,
+// see Generator.AddLayout.
+func (rs *Routes[T]) Layout(name string) *Routes[T] {
+	rs.layout = name
+	return rs
+}
+
+// Slot sets the component of the layout's slot name for each item route.
+func (rs *Routes[T]) Slot(name string, comp templ.Component) *Routes[T] {
+	if rs.slots == nil {
+		rs.slots = map[string]templ.Component{}
+	}
+	rs.slots[name] = comp
+	return rs
+}
+
+// SetData sets value with key to each item route, see Route.SetData.
+func (rs *Routes[T]) SetData(key string, value any) *Routes[T] {
+	if rs.userData == nil {
+		rs.userData = map[string]any{}
+	}
+	rs.userData[key] = value
+	return rs
+}
+
+// SetWeight sets the weight of each item route's navigation item, see NavItem.
+func (rs *Routes[T]) SetWeight(weight int) *Routes[T] {
+	rs.weight = weight
+	return rs
+}
+
+// Menu adds each item route's navigation item to the menus, see GetMenu.
+func (rs *Routes[T]) Menu(names ...string) *Routes[T] {
+	rs.menus = append(rs.menus, names...)
+	return rs
+}
+
 type RouteFunc[T any] = func(context.Context, *RouteParam[T]) (templ.Component, error)
 
 // RouteParam is the current route parameter.
@@ -137,13 +239,35 @@ type RouteParam[T any] struct {
 	title string
 
 	baseConfig *temfest.BaseConfig
+	meta       *temfest.Meta
+	header     http.Header
 	HeadBody   HeadBody
+
+	layout string
+	slots  map[string]templ.Component
+
+	userData map[string]any
+	weight   int
+	menus    []string
 }
 
 // BaseConfig sets the temfest.Base config for the current route.
-// Unset/empty field will be no-op.
+// Unset/empty field will be inherited from the Routes' BaseConfig.
 func (rp *RouteParam[T]) BaseConfig(conf temfest.BaseConfig) { rp.baseConfig = &conf }
 
+// SetHeader sets a response header for the current route. It overrides
+// the header with the same key set for Routes.
+func (rp *RouteParam[T]) SetHeader(key, value string) {
+	if rp.header == nil {
+		rp.header = http.Header{}
+	}
+	rp.header.Set(key, value)
+}
+
+// SetMeta sets the page metadata for the current route.
+// Unset/empty field will be inherited from the Routes' metadata.
+func (rp *RouteParam[T]) SetMeta(meta temfest.Meta) { rp.meta = &meta }
+
 // GetSlug gets the currently set slug.
 func (rp *RouteParam[T]) GetSlug() string { return rp.slug }
 
@@ -155,3 +279,32 @@ func (rp *RouteParam[T]) GetItem() T { return rp.item }
 
 // SetTitle sets the current route title. It overrides the title sets for Routes.
 func (rp *RouteParam[T]) SetTitle(title string) { rp.title = title }
+
+// Layout sets the layout of the current route. It overrides the layout set for Routes.
+func (rp *RouteParam[T]) Layout(name string) { rp.layout = name }
+
+// Slot sets the component of the layout's slot name for the current route.
+// It overrides the slot with the same name set for Routes.
+func (rp *RouteParam[T]) Slot(name string, comp templ.Component) {
+	if rp.slots == nil {
+		rp.slots = map[string]templ.Component{}
+	}
+	rp.slots[name] = comp
+}
+
+// SetData sets value with key to the current route, see Route.SetData.
+// It overrides the data with the same key set for Routes.
+func (rp *RouteParam[T]) SetData(key string, value any) {
+	if rp.userData == nil {
+		rp.userData = map[string]any{}
+	}
+	rp.userData[key] = value
+}
+
+// SetWeight sets the weight of the current route's navigation item.
+// It overrides the weight set for Routes.
+func (rp *RouteParam[T]) SetWeight(weight int) { rp.weight = weight }
+
+// Menu adds the current route's navigation item to the menus
+// in addition to the ones set for Routes.
+func (rp *RouteParam[T]) Menu(names ...string) { rp.menus = append(rp.menus, names...) }
@@ -0,0 +1,23 @@
+package temfest
+
+// Bool is a tri-state boolean used by configs that are inherited, such as
+// BaseConfig, so a child config can explicitly turn off what its parent
+// turned on. The zero value is Unset, which inherits the parent's value.
+type Bool uint8
+
+const (
+	Unset Bool = iota
+	True
+	False
+)
+
+// IsTrue reports whether b is True. Unset is considered false.
+func (b Bool) IsTrue() bool { return b == True }
+
+// BoolOf converts v into True or False.
+func BoolOf(v bool) Bool {
+	if v {
+		return True
+	}
+	return False
+}
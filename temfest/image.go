@@ -0,0 +1,70 @@
+package temfest
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Image is an image with its resized variants, used by Img and Picture.
+type Image struct {
+	// Src is the URL of the fallback image.
+	Src string
+
+	// Alt is the alternative text of the image.
+	Alt string
+
+	// Width and Height are the intrinsic size of the fallback image.
+	Width, Height int
+
+	// Sizes is the sizes attribute, e.g. "(min-width: 800px) 50vw, 100vw".
+	Sizes string
+
+	// Variants are the resized versions of the image.
+	Variants []ImageVariant
+}
+
+// ImageVariant is a resized version of an Image.
+type ImageVariant struct {
+	// URL of the variant.
+	URL string
+
+	// Width and Height are the size of the variant in pixels.
+	Width, Height int
+
+	// Type is the MIME type of the variant, e.g. "image/jpeg".
+	Type string
+}
+
+// Srcset returns the value of srcset attribute of all variants.
+func (img Image) Srcset() string { return srcset(img.Variants) }
+
+// types returns the MIME types of the variants in order of appearance.
+func (img Image) types() []string {
+	var types []string
+	for _, v := range img.Variants {
+		if !slices.Contains(types, v.Type) {
+			types = append(types, v.Type)
+		}
+	}
+	return types
+}
+
+// srcsetOf returns the value of srcset attribute of the variants with type t.
+func (img Image) srcsetOf(t string) string {
+	var variants []ImageVariant
+	for _, v := range img.Variants {
+		if v.Type == t {
+			variants = append(variants, v)
+		}
+	}
+	return srcset(variants)
+}
+
+func srcset(variants []ImageVariant) string {
+	candidates := make([]string, 0, len(variants))
+	for _, v := range variants {
+		candidates = append(candidates, fmt.Sprintf("%v %dw", v.URL, v.Width))
+	}
+	return strings.Join(candidates, ", ")
+}
@@ -0,0 +1,159 @@
+package temfest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/a-h/templ"
+)
+
+// JSONLD renders data as a `<script type="application/ld+json">` tag with
+// the schema.org context, e.g. one of Article, BreadcrumbList, Organization
+// and WebSite. The JSON is escaped so it's safe to be put inside the tag.
+func JSONLD(data any) templ.Component {
+	return templ.ComponentFunc(func(ctx context.Context, w io.Writer) error {
+		b, err := json.Marshal(data)
+		if err != nil {
+			return err
+		}
+		if bytes.HasPrefix(b, []byte("{")) {
+			ld := []byte(`{"@context":"https://schema.org"`)
+			if !bytes.Equal(b, []byte("{}")) {
+				ld = append(ld, ',')
+			}
+			b = append(ld, b[1:]...)
+		}
+		return templ.JSONScript("", json.RawMessage(b)).
+			WithType("application/ld+json").
+			Render(ctx, w)
+	})
+}
+
+// Person is schema.org Person.
+type Person struct {
+	Name string
+	URL  string
+}
+
+func (p Person) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Type string `json:"@type"`
+		Name string `json:"name,omitempty"`
+		URL  string `json:"url,omitempty"`
+	}{"Person", p.Name, p.URL})
+}
+
+// Organization is schema.org Organization.
+type Organization struct {
+	Name string
+	URL  string
+
+	// Logo is the URL of the organization's logo.
+	Logo string
+
+	// SameAs are the URLs of the organization's other pages, e.g. social media.
+	SameAs []string
+}
+
+func (o Organization) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Type   string   `json:"@type"`
+		Name   string   `json:"name,omitempty"`
+		URL    string   `json:"url,omitempty"`
+		Logo   string   `json:"logo,omitempty"`
+		SameAs []string `json:"sameAs,omitempty"`
+	}{"Organization", o.Name, o.URL, o.Logo, o.SameAs})
+}
+
+// WebSite is schema.org WebSite.
+type WebSite struct {
+	Name        string
+	URL         string
+	Description string
+	Publisher   *Organization
+}
+
+func (ws WebSite) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Type        string        `json:"@type"`
+		Name        string        `json:"name,omitempty"`
+		URL         string        `json:"url,omitempty"`
+		Description string        `json:"description,omitempty"`
+		Publisher   *Organization `json:"publisher,omitempty"`
+	}{"WebSite", ws.Name, ws.URL, ws.Description, ws.Publisher})
+}
+
+// Article is schema.org Article.
+type Article struct {
+	Headline    string
+	Description string
+
+	// URL is the canonical URL of the article, used for mainEntityOfPage.
+	URL string
+
+	// Image are the URLs of the article's images.
+	Image []string
+
+	Author    []Person
+	Publisher *Organization
+
+	DatePublished time.Time
+	DateModified  time.Time
+}
+
+func (a Article) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Type          string        `json:"@type"`
+		Headline      string        `json:"headline,omitempty"`
+		Description   string        `json:"description,omitempty"`
+		URL           string        `json:"mainEntityOfPage,omitempty"`
+		Image         []string      `json:"image,omitempty"`
+		Author        []Person      `json:"author,omitempty"`
+		Publisher     *Organization `json:"publisher,omitempty"`
+		DatePublished string        `json:"datePublished,omitempty"`
+		DateModified  string        `json:"dateModified,omitempty"`
+	}{
+		"Article", a.Headline, a.Description, a.URL, a.Image, a.Author, a.Publisher,
+		formatTime(a.DatePublished), formatTime(a.DateModified),
+	})
+}
+
+// BreadcrumbList is schema.org BreadcrumbList.
+type BreadcrumbList struct {
+	// Items are ordered from the top-level page to the current page.
+	Items []Breadcrumb
+}
+
+// Breadcrumb is an item of BreadcrumbList.
+type Breadcrumb struct {
+	Name string
+	URL  string
+}
+
+func (bl BreadcrumbList) MarshalJSON() ([]byte, error) {
+	type listItem struct {
+		Type     string `json:"@type"`
+		Position int    `json:"position"`
+		Name     string `json:"name"`
+		Item     string `json:"item,omitempty"`
+	}
+	items := make([]listItem, 0, len(bl.Items))
+	for i, v := range bl.Items {
+		items = append(items, listItem{"ListItem", i + 1, v.Name, v.URL})
+	}
+
+	return json.Marshal(struct {
+		Type  string     `json:"@type"`
+		Items []listItem `json:"itemListElement"`
+	}{"BreadcrumbList", items})
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
@@ -0,0 +1,46 @@
+package temfest
+
+import "time"
+
+// Meta is the metadata of a page rendered by MetaTags, including
+// the Open Graph and Twitter card properties.
+type Meta struct {
+	// Title is og:title. By default the Generator sets it to the page title.
+	Title string
+
+	// SiteName is og:site_name. By default the Generator sets it to the site's name.
+	SiteName string
+
+	// Description is the page description, also used for og:description.
+	Description string
+
+	// CanonicalURL is the absolute URL of the page, also used for og:url.
+	CanonicalURL string
+
+	// Type is og:type, e.g. "website" or "article".
+	Type string
+
+	// Image is og:image.
+	Image MetaImage
+
+	// TwitterCard is twitter:card, e.g. "summary_large_image".
+	TwitterCard string
+
+	// Author is the name of the page author.
+	Author string
+
+	// PublishedTime is article:published_time.
+	PublishedTime time.Time
+}
+
+// MetaImage is the image representing a page.
+type MetaImage struct {
+	// URL is the absolute URL of the image.
+	URL string
+
+	// Width and Height are the size of the image in pixels.
+	Width, Height int
+
+	// Alt is the alternative text of the image.
+	Alt string
+}
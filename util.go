@@ -1,11 +1,8 @@
 package fest
 
 import (
-	"errors"
 	"fmt"
-	"io"
 	"os"
-	"path/filepath"
 	"reflect"
 )
 
@@ -17,81 +14,49 @@ func ternary[T any](cond bool, t T, f T) T {
 }
 
 // inheritChildValues set the corresponding parent's field value to child's
-// if the latter is not empty.
-func inheritChildValues[T any](parent, child T) {
-	parentVal := reflect.ValueOf(parent).Elem()
-	childVal := reflect.ValueOf(child).Elem()
+// if the latter is not empty. Nested structs with only exported fields,
+// such as temfest.Meta, are inherited field by field, and maps, such as
+// templ.Attributes, are merged into a new map with child's values taking
+// precedence. Tri-state fields like temfest.Bool are only empty when unset,
+// so the child can explicitly reset them.
+func inheritChildValues[T any](parent, child *T) {
+	inherit(reflect.ValueOf(parent).Elem(), reflect.ValueOf(child).Elem())
+}
 
+func inherit(parentVal, childVal reflect.Value) {
 	for i := range parentVal.NumField() {
 		parentField := parentVal.Field(i)
 		childField := childVal.Field(i)
 
-		if !childField.IsZero() && parentField.Interface() != childField.Interface() {
-			parentField.Set(childVal)
+		if childField.IsZero() {
+			continue
 		}
-	}
-}
-
-func copyFile(src, dst string) error {
-	srcFile, err := os.Open(src)
-	if err != nil {
-		return err
-	}
-	defer srcFile.Close()
-
-	srcInfo, err := srcFile.Stat()
-	if err != nil {
-		return err
-	}
-
-	dstFile, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, srcInfo.Mode())
-	if err != nil {
-		return err
-	}
-	defer dstFile.Close()
-
-	_, err = io.Copy(dstFile, srcFile)
-	return err
-}
-
-func copyDir(src, dst string) error {
-	src = filepath.Clean(src)
-	dst = filepath.Clean(dst)
-
-	srcInfo, err := os.Stat(src)
-	if err != nil {
-		return err
-	}
-
-	if !srcInfo.IsDir() {
-		return errors.New("source is not a directory")
-	}
-
-	err = os.MkdirAll(dst, srcInfo.Mode())
-	if err != nil {
-		return err
-	}
-
-	return filepath.WalkDir(src, func(path string, d os.DirEntry, err error) error {
-		if err != nil {
-			return err
+		if childField.Kind() == reflect.Struct && exportedOnly(childField.Type()) {
+			inherit(parentField, childField)
+			continue
 		}
-
-		relPath, err := filepath.Rel(src, path)
-		if err != nil {
-			return err
+		if childField.Kind() == reflect.Map && !parentField.IsZero() {
+			merged := reflect.MakeMapWithSize(parentField.Type(), parentField.Len()+childField.Len())
+			for _, m := range []reflect.Value{parentField, childField} {
+				for iter := m.MapRange(); iter.Next(); {
+					merged.SetMapIndex(iter.Key(), iter.Value())
+				}
+			}
+			parentField.Set(merged)
+			continue
 		}
-		dstPath := filepath.Join(dst, relPath)
+		parentField.Set(childField)
+	}
+}
 
-		if d.IsDir() {
-			info, err := d.Info()
-			if err != nil {
-				return err
-			}
-			return os.MkdirAll(dstPath, info.Mode())
+// exportedOnly reports whether all fields of the struct type t are exported.
+func exportedOnly(t reflect.Type) bool {
+	for i := range t.NumField() {
+		if !t.Field(i).IsExported() {
+			return false
 		}
-		return copyFile(path, dstPath)
-	})
+	}
+	return true
 }
 
 func fatal(err error) {